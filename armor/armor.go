@@ -6,6 +6,8 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"strconv"
+	"strings"
 
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/ProtonMail/gopenpgp/v2/constants"
@@ -13,6 +15,22 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	armorBeginPrefix = "-----BEGIN "
+	armorDashes      = "-----"
+)
+
+// trailingNewline controls whether armored outputs end with a newline after
+// the END line.
+var trailingNewline = false
+
+// SetTrailingNewline sets whether armored outputs end with a newline after the
+// END line. By default no trailing newline is written.
+// It is not safe to call concurrently with the armoring functions.
+func SetTrailingNewline(enabled bool) {
+	trailingNewline = enabled
+}
+
 // ArmorKey armors input as a public key.
 func ArmorKey(input []byte) (string, error) {
 	return ArmorWithType(input, constants.PublicKeyHeader)
@@ -21,7 +39,11 @@ func ArmorKey(input []byte) (string, error) {
 // ArmorWithTypeBuffered returns a io.WriteCloser which, when written to, writes
 // armored data to w with the given armorType.
 func ArmorWithTypeBuffered(w io.Writer, armorType string) (io.WriteCloser, error) {
-	return armor.Encode(w, armorType, nil)
+	armorWriter, err := armor.Encode(w, armorType, nil)
+	if err != nil || !trailingNewline {
+		return armorWriter, err
+	}
+	return &trailingNewlineWriter{armorWriter, w}, nil
 }
 
 // ArmorWithType armors input with the given armorType.
@@ -30,8 +52,11 @@ func ArmorWithType(input []byte, armorType string) (string, error) {
 }
 
 // ArmorWithTypeAndCustomHeaders armors input with the given armorType and
-// headers.
+// headers. The headers must not contain newlines.
 func ArmorWithTypeAndCustomHeaders(input []byte, armorType, version, comment string) (string, error) {
+	if strings.ContainsAny(version, "\r\n") || strings.ContainsAny(comment, "\r\n") {
+		return "", errors.New("gopenpgp: armor headers must not contain newlines")
+	}
 	headers := make(map[string]string)
 	if version != "" {
 		headers["Version"] = version
@@ -42,6 +67,23 @@ func ArmorWithTypeAndCustomHeaders(input []byte, armorType, version, comment str
 	return armorWithTypeAndHeaders(input, armorType, headers)
 }
 
+// ArmorWithTypeAndHeaders armors input with the given armorType and headers,
+// in addition to the default headers. The headers must not contain newlines,
+// and their keys must not contain colons.
+func ArmorWithTypeAndHeaders(input []byte, armorType string, headers map[string]string) (string, error) {
+	allHeaders := make(map[string]string)
+	for key, value := range internal.ArmorHeaders {
+		allHeaders[key] = value
+	}
+	for key, value := range headers {
+		if strings.ContainsAny(key, ":\r\n") || strings.ContainsAny(value, "\r\n") {
+			return "", errors.New("gopenpgp: invalid armor header " + strconv.Quote(key))
+		}
+		allHeaders[key] = value
+	}
+	return armorWithTypeAndHeaders(input, armorType, allHeaders)
+}
+
 // Unarmor unarmors an armored input into a byte array.
 func Unarmor(input string) ([]byte, error) {
 	b, err := internal.Unarmor(input)
@@ -51,6 +93,63 @@ func Unarmor(input string) ([]byte, error) {
 	return ioutil.ReadAll(b.Body)
 }
 
+// ErrUnexpectedArmorType is returned by ExpectArmorType when the armored
+// input is not of the expected type.
+var ErrUnexpectedArmorType = errors.New("gopenpgp: unexpected armor type")
+
+// ExpectArmorType checks that the armored input is of the expected armor type,
+// e.g. constants.PGPMessageHeader, without decoding its body.
+// Any text before the BEGIN line is ignored, as when unarmoring.
+// ErrUnexpectedArmorType is returned if the type does not match, and an
+// error if the input has no BEGIN line.
+func ExpectArmorType(armored string, expected string) error {
+	armorType, err := getArmorType(armored)
+	if err != nil {
+		return err
+	}
+	if armorType != expected {
+		return ErrUnexpectedArmorType
+	}
+	return nil
+}
+
+// armoredKinds maps armor types to the kinds returned by IdentifyArmored.
+var armoredKinds = map[string]string{
+	constants.PGPMessageHeader:       constants.ArmoredMessage,
+	constants.PGPSignedMessageHeader: constants.ArmoredSignedMessage,
+	constants.PublicKeyHeader:        constants.ArmoredPublicKey,
+	constants.PrivateKeyHeader:       constants.ArmoredPrivateKey,
+	constants.PGPSignatureHeader:     constants.ArmoredSignature,
+}
+
+// IdentifyArmored returns the kind of the armored input, one of
+// constants.ArmoredMessage, ArmoredSignedMessage, ArmoredPublicKey,
+// ArmoredPrivateKey and ArmoredSignature, without decoding its body.
+// Any text before the BEGIN line is ignored, as when unarmoring.
+func IdentifyArmored(armored string) (string, error) {
+	armorType, err := getArmorType(armored)
+	if err != nil {
+		return "", err
+	}
+	kind, ok := armoredKinds[armorType]
+	if !ok {
+		return "", errors.New("gopenpgp: unknown armor type " + strconv.Quote(armorType))
+	}
+	return kind, nil
+}
+
+// getArmorType returns the type of the first BEGIN line of the armored input.
+func getArmorType(armored string) (string, error) {
+	for _, line := range strings.Split(armored, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if !strings.HasPrefix(line, armorBeginPrefix) || !strings.HasSuffix(line, armorDashes) {
+			continue
+		}
+		return line[len(armorBeginPrefix) : len(line)-len(armorDashes)], nil
+	}
+	return "", errors.New("gopenpgp: no armored data found")
+}
+
 func armorWithTypeAndHeaders(input []byte, armorType string, headers map[string]string) (string, error) {
 	var b bytes.Buffer
 
@@ -65,5 +164,23 @@ func armorWithTypeAndHeaders(input []byte, armorType string, headers map[string]
 	if err := w.Close(); err != nil {
 		return "", errors.Wrap(err, "gopengp: unable to close armor buffer")
 	}
+	if trailingNewline {
+		b.WriteByte('\n')
+	}
 	return b.String(), nil
 }
+
+// trailingNewlineWriter writes a newline to the underlying writer once the
+// armor writer is closed.
+type trailingNewlineWriter struct {
+	io.WriteCloser
+	out io.Writer
+}
+
+func (w *trailingNewlineWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	_, err := w.out.Write([]byte{'\n'})
+	return err
+}
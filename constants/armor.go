@@ -9,4 +9,16 @@ const (
 	PGPSignatureHeader = "PGP SIGNATURE"
 	PublicKeyHeader    = "PGP PUBLIC KEY BLOCK"
 	PrivateKeyHeader   = "PGP PRIVATE KEY BLOCK"
+	// PGPSignedMessageHeader begins a cleartext signed message, which is not
+	// fully armored.
+	PGPSignedMessageHeader = "PGP SIGNED MESSAGE"
+)
+
+// Kinds of armored data returned by armor.IdentifyArmored.
+const (
+	ArmoredMessage       = "message"
+	ArmoredSignedMessage = "signed_message"
+	ArmoredPublicKey     = "public_key"
+	ArmoredPrivateKey    = "private_key"
+	ArmoredSignature     = "signature"
 )
@@ -69,6 +69,10 @@ func (ap *AttachmentProcessor) Finish() (*PGPSplitMessage, error) {
 func (keyRing *KeyRing) newAttachmentProcessor(
 	estimatedSize int, filename string, isBinary bool, modTime uint32, garbageCollector int,
 ) (*AttachmentProcessor, error) {
+	if err := keyRing.checkNotRevoked(); err != nil {
+		return nil, err
+	}
+
 	attachmentProc := &AttachmentProcessor{}
 	// You could also add these one at a time if needed.
 	attachmentProc.done.Add(1)
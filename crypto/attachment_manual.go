@@ -77,6 +77,9 @@ func (keyRing *KeyRing) NewManualAttachmentProcessor(
 	if len(dataBuffer) == 0 {
 		return nil, errors.New("gopenpgp: can't give a nil or empty buffer to process the attachement")
 	}
+	if err := keyRing.checkNotRevoked(); err != nil {
+		return nil, err
+	}
 
 	// forces the gc to be called often
 	debug.SetGCPercent(10)
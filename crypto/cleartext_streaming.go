@@ -0,0 +1,298 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpArmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+	"github.com/pkg/errors"
+)
+
+const (
+	cleartextBeginLine     = "-----BEGIN PGP SIGNED MESSAGE-----"
+	cleartextSignatureLine = "-----BEGIN PGP SIGNATURE-----"
+)
+
+// cleartextHashes maps the values of the Hash armor header to the allowed
+// hash functions.
+var cleartextHashes = map[string]crypto.Hash{
+	"SHA224": crypto.SHA224,
+	"SHA256": crypto.SHA256,
+	"SHA384": crypto.SHA384,
+	"SHA512": crypto.SHA512,
+}
+
+// ClearTextMessageReader reads the text of a cleartext signed message,
+// and verifies its signature once the text has been read.
+type ClearTextMessageReader struct {
+	reader        *bufio.Reader
+	hashes        map[crypto.Hash]hash.Hash
+	verifyKeyRing *KeyRing
+	verifyTime    int64
+
+	pending      []byte
+	pendingSpace []byte
+	firstLine    bool
+	inLine       bool
+	bodyRead     bool
+	readAll      bool
+}
+
+// VerifyCleartextStream reads a cleartext signed message from message,
+// and returns a ClearTextMessageReader for its text.
+// The text is hashed while it is read, so that the memory used does not
+// depend on the size of the message. It is returned without dash-escaping,
+// in the canonical form which is signed, i.e. with trailing whitespace removed
+// and lines separated by "\r\n", as in ClearTextMessage.GetBinary().
+// ClearTextMessageReader.VerifySignature() verifies the signature with the
+// keyring and the given verification time once the text has been read.
+func (keyRing *KeyRing) VerifyCleartextStream(message Reader, verifyTime int64) (*ClearTextMessageReader, error) {
+	reader := bufio.NewReader(message)
+
+	// Skip any data before the start of the message
+	for {
+		line, err := readFullLine(reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to find cleartext message")
+		}
+		if string(line) == cleartextBeginLine {
+			break
+		}
+	}
+
+	hashes := make(map[crypto.Hash]hash.Hash)
+	for {
+		line, err := readFullLine(reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in reading cleartext message headers")
+		}
+		if len(line) == 0 {
+			break
+		}
+
+		i := bytes.IndexByte(line, ':')
+		if i == -1 || strings.TrimSpace(string(line[:i])) != "Hash" {
+			return nil, errors.New("gopenpgp: invalid cleartext message header")
+		}
+		for _, name := range strings.Split(string(line[i+1:]), ",") {
+			if hashFunc, ok := cleartextHashes[strings.TrimSpace(name)]; ok {
+				hashes[hashFunc] = hashFunc.New()
+			}
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, newSignatureInsecure()
+	}
+
+	return &ClearTextMessageReader{
+		reader:        reader,
+		hashes:        hashes,
+		verifyKeyRing: keyRing,
+		verifyTime:    verifyTime,
+		firstLine:     true,
+	}, nil
+}
+
+// Read is used to access the text of the message.
+// Makes ClearTextMessageReader implement the Reader interface.
+func (msg *ClearTextMessageReader) Read(b []byte) (n int, err error) {
+	for len(msg.pending) == 0 && !msg.bodyRead {
+		if err = msg.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(msg.pending) == 0 {
+		msg.readAll = true
+		return 0, io.EOF
+	}
+
+	n = copy(b, msg.pending)
+	msg.pending = msg.pending[n:]
+	return n, nil
+}
+
+// VerifySignature is used to verify that the signature is valid.
+// This method needs to be called once all the text has been read.
+// It will return an error if the signature is invalid
+// or if the text hasn't been read entirely.
+func (msg *ClearTextMessageReader) VerifySignature() error {
+	if !msg.readAll {
+		return errors.New("gopenpgp: can't verify the signature until the message reader has been read entirely")
+	}
+
+	armoredSignature := io.MultiReader(strings.NewReader(cleartextSignatureLine+"\n"), msg.reader)
+	block, err := pgpArmor.Decode(armoredSignature)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to unarmor signature")
+	}
+
+	var signature *packet.Signature
+	var keys []openpgp.Key
+	packets := packet.NewReader(block.Body)
+	for len(keys) == 0 {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			if signature == nil {
+				return newSignatureNotSigned()
+			}
+			return newSignatureNoVerifier()
+		}
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: error in reading signature")
+		}
+
+		var ok bool
+		if signature, ok = p.(*packet.Signature); ok && signature.IssuerKeyId != nil {
+			keys = msg.verifyKeyRing.entities.KeysByIdUsage(*signature.IssuerKeyId, packet.KeyFlagSign)
+		}
+	}
+
+	details := &openpgp.MessageDetails{
+		IsSigned:      true,
+		SignedByKeyId: *signature.IssuerKeyId,
+		SignedBy:      &keys[0],
+		Signature:     signature,
+	}
+
+	hashFunc, ok := msg.hashes[signature.Hash]
+	switch {
+	case !ok:
+		details.SignatureError = errors.New("gopenpgp: signature hash is not listed in the message headers")
+	case signature.SigType != packet.SigTypeText && signature.SigType != packet.SigTypeBinary:
+		details.SignatureError = errors.New("gopenpgp: unsupported signature type")
+	default:
+		for i := range keys {
+			// Each verification hashes the signature trailer, use a copy of the text hash
+			signed, err := cloneHash(signature.Hash, hashFunc)
+			if err != nil {
+				return err
+			}
+			details.SignedBy = &keys[i]
+			details.SignatureError = keys[i].PublicKey.VerifySignature(signed, signature)
+			if details.SignatureError == nil {
+				details.SignatureError = checkSignatureTime(signature, keys[i], msg.verifyTime)
+			}
+			if details.SignatureError == nil {
+				break
+			}
+		}
+	}
+
+	return verifyDetailsSignature(details, msg.verifyKeyRing)
+}
+
+// checkSignatureTime checks that a signature made by key is valid at
+// verifyTime, like verifySignature: the signature must not be expired, and
+// the key must be neither expired nor revoked, otherwise ErrSignatureExpired,
+// ErrKeyExpired or ErrKeyRevoked is returned. If verifyTime is 0 the time
+// checks are disabled, but revoked keys are still refused.
+func checkSignatureTime(signature *packet.Signature, key openpgp.Key, verifyTime int64) error {
+	now := time.Unix(verifyTime+internal.CreationTimeOffset, 0)
+	revoked, expiredNow := keyStatusAt(key, now)
+	if revoked {
+		return pgpErrors.ErrKeyRevoked
+	}
+	if verifyTime == 0 {
+		return nil
+	}
+
+	// Maybe the creation time offset pushed it over the edge,
+	// the signature or key is only expired if it is at the actual verification time
+	if signature.SigExpired(now) && signature.SigExpired(time.Unix(verifyTime, 0)) {
+		return pgpErrors.ErrSignatureExpired
+	}
+	if _, expired := keyStatusAt(key, time.Unix(verifyTime, 0)); expiredNow && expired {
+		return pgpErrors.ErrKeyExpired
+	}
+	return nil
+}
+
+// cloneHash returns a copy of h, a hash of hashFunc.
+func cloneHash(hashFunc crypto.Hash, h hash.Hash) (hash.Hash, error) {
+	marshaler, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, errors.New("gopenpgp: unable to copy the state of the hash")
+	}
+	state, err := marshaler.MarshalBinary()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to copy the state of the hash")
+	}
+
+	clone := hashFunc.New()
+	unmarshaler, ok := clone.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return nil, errors.New("gopenpgp: unable to copy the state of the hash")
+	}
+	if err := unmarshaler.UnmarshalBinary(state); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to copy the state of the hash")
+	}
+	return clone, nil
+}
+
+// readChunk reads the next chunk of the text, and appends it to the pending
+// data in its canonical form.
+func (msg *ClearTextMessageReader) readChunk() error {
+	chunk, isPrefix, err := msg.reader.ReadLine()
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: error in reading cleartext message")
+	}
+
+	if !msg.inLine {
+		if !isPrefix && string(bytes.TrimRight(chunk, " \t\r")) == cleartextSignatureLine {
+			msg.bodyRead = true
+			return nil
+		}
+		if !msg.firstLine {
+			msg.write([]byte("\r\n"))
+		}
+		msg.firstLine = false
+		chunk = bytes.TrimPrefix(chunk, []byte("- "))
+	}
+
+	// Trailing whitespace is removed, but it is only known to be trailing
+	// once the end of the line is reached.
+	data := append(msg.pendingSpace, chunk...)
+	text := bytes.TrimRight(data, " \t\r")
+	msg.write(text)
+	msg.pendingSpace = nil
+	if isPrefix {
+		msg.pendingSpace = append([]byte{}, data[len(text):]...)
+	}
+	msg.inLine = isPrefix
+	return nil
+}
+
+// write hashes data and appends it to the pending data.
+func (msg *ClearTextMessageReader) write(data []byte) {
+	for _, h := range msg.hashes {
+		_, _ = h.Write(data)
+	}
+	msg.pending = append(msg.pending, data...)
+}
+
+// readFullLine reads a line from reader, and returns it without the line
+// ending and the trailing whitespace.
+func readFullLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, chunk...)
+		if !isPrefix {
+			return bytes.TrimRight(line, " \t\r"), nil
+		}
+	}
+}
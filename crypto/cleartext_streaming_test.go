@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing_VerifyCleartextStream(t *testing.T) {
+	longLine := strings.Repeat("long line ", 500) + "  \t"
+	text := "- dash escaped line\nline with trailing spaces   \r\n" + longLine + "\n-----BEGIN not a signature\nlast line"
+
+	var armored bytes.Buffer
+	config := &packet.Config{DefaultHash: crypto.SHA256, Time: getTimeGenerator()}
+	writer, err := clearsign.Encode(&armored, keyRingTestPrivate.entities[0].PrivateKey, config)
+	if err != nil {
+		t.Fatal("Expected no error while creating cleartext signer, got:", err)
+	}
+	if _, err = writer.Write([]byte(text)); err != nil {
+		t.Fatal("Expected no error while signing cleartext, got:", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing cleartext signer, got:", err)
+	}
+
+	block, _ := clearsign.Decode(armored.Bytes())
+	if block == nil {
+		t.Fatal("Expected a cleartext message")
+	}
+
+	reader, err := keyRingTestPublic.VerifyCleartextStream(bytes.NewReader(armored.Bytes()), GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while reading cleartext message, got:", err)
+	}
+	assert.NotNil(t, reader.VerifySignature())
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading cleartext body, got:", err)
+	}
+	assert.Exactly(t, block.Bytes, body)
+
+	if err = reader.VerifySignature(); err != nil {
+		t.Fatal("Expected no error while verifying cleartext signature, got:", err)
+	}
+
+	tampered := bytes.Replace(armored.Bytes(), []byte("last line"), []byte("last lime"), 1)
+	reader, err = keyRingTestPublic.VerifyCleartextStream(bytes.NewReader(tampered), GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while reading cleartext message, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading cleartext body, got:", err)
+	}
+	assert.IsType(t, SignatureVerificationError{}, reader.VerifySignature())
+
+	verifyCleartext := func(keyRing *KeyRing, verifyTime int64) error {
+		reader, err := keyRing.VerifyCleartextStream(bytes.NewReader(armored.Bytes()), verifyTime)
+		if err != nil {
+			t.Fatal("Expected no error while reading cleartext message, got:", err)
+		}
+		if _, err = ioutil.ReadAll(reader); err != nil {
+			t.Fatal("Expected no error while reading cleartext body, got:", err)
+		}
+		return reader.VerifySignature()
+	}
+	assert.Nil(t, verifyCleartext(keyRingTestPublic, 0))
+	assert.IsType(t, SignatureVerificationError{}, verifyCleartext(keyRingTestPublic, GetUnixTime()-10*internal.CreationTimeOffset))
+
+	// All the candidate keys with the issuer key ID are tried
+	impostor := *keyTestRSA.entity
+	impostorKey := *impostor.PrimaryKey
+	impostorKey.KeyId = keyRingTestPublic.entities[0].PrimaryKey.KeyId
+	impostor.PrimaryKey = &impostorKey
+	assert.Nil(t, verifyCleartext(&KeyRing{entities: openpgp.EntityList{&impostor, keyRingTestPublic.entities[0]}}, GetUnixTime()))
+
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	reader, err = ecKeyRing.VerifyCleartextStream(bytes.NewReader(armored.Bytes()), GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while reading cleartext message, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading cleartext body, got:", err)
+	}
+	assert.Exactly(t, newSignatureNoVerifier(), reader.VerifySignature())
+
+	_, err = keyRingTestPublic.VerifyCleartextStream(strings.NewReader("not a cleartext message"), GetUnixTime())
+	assert.NotNil(t, err)
+}
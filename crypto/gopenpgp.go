@@ -6,6 +6,7 @@ package crypto
 type GopenPGP struct {
 	latestServerTime int64
 	generationOffset int64
+	timeOffset       int64
 }
 
 var pgp = GopenPGP{}
@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"bufio"
 	"bytes"
 	"crypto"
 	"crypto/sha256"
@@ -16,9 +17,18 @@ import (
 	"github.com/pkg/errors"
 
 	openpgp "github.com/ProtonMail/go-crypto/openpgp"
+	pgpArmor "github.com/ProtonMail/go-crypto/openpgp/armor"
 	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
 )
 
+// ErrUnsupportedKeyVersion is returned when reading a key whose version,
+// e.g. OpenPGP v6, is not supported.
+var ErrUnsupportedKeyVersion = errors.New("gopenpgp: unsupported key version")
+
+// ErrNoArmoredKey is returned when reading an armored key from data that does
+// not contain an armored public or private key block.
+var ErrNoArmoredKey = errors.New("gopenpgp: no armored key found")
+
 // Key contains a single private or public key.
 type Key struct {
 	// PGP entities in this keyring.
@@ -55,8 +65,16 @@ func NewKey(binKeys []byte) (key *Key, err error) {
 }
 
 // NewKeyFromArmored creates a new key from the first key in an armored string.
+// Text around the armored block, whitespace around its lines and the line
+// endings are ignored, so that keys pasted from emails or documents can be
+// read. ErrNoArmoredKey is returned if armored contains no armored public or
+// private key block.
 func NewKeyFromArmored(armored string) (key *Key, err error) {
-	return NewKeyFromArmoredReader(strings.NewReader(armored))
+	block, ok := findArmoredKeyBlock(normalizeArmoredKey(armored))
+	if !ok {
+		return nil, ErrNoArmoredKey
+	}
+	return NewKeyFromArmoredReader(strings.NewReader(block))
 }
 
 func NewKeyFromEntity(entity *openpgp.Entity) (*Key, error) {
@@ -82,6 +100,102 @@ func GenerateKey(name, email string, keyType string, bits int) (*Key, error) {
 	return generateKey(name, email, keyType, bits, nil, nil, nil, nil)
 }
 
+// GenerateKeyWithIdentities generates a key of the given keyType ("rsa" or "x25519")
+// with all the given identities, each with its own self-signature.
+// The first identity is marked as primary.
+// If keyType is "rsa", bits is the RSA bitsize of the key.
+// If keyType is "x25519" bits is unused.
+func GenerateKeyWithIdentities(identities []*Identity, keyType string, bits int) (*Key, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("gopenpgp: no identity provided")
+	}
+
+	key, err := generateKey(identities[0].Name, identities[0].Email, keyType, bits, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &packet.Config{Time: getKeyGenerationTimeGenerator()}
+	for _, identity := range identities[1:] {
+		if err := addIdentity(key.entity, identity, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// addIdentity adds a non-primary identity to a private entity, with a
+// self-signature sharing the preferences, flags and expiration of the
+// primary identity.
+func addIdentity(entity *openpgp.Entity, identity *Identity, cfg *packet.Config) error {
+	if len(identity.Email) == 0 {
+		return errors.New("gopenpgp: invalid email format")
+	}
+
+	uid := packet.NewUserId(identity.Name, "", identity.Email)
+	if uid == nil {
+		return errors.New("gopenpgp: invalid identity format")
+	}
+	if _, ok := entity.Identities[uid.Id]; ok {
+		return errors.New("gopenpgp: duplicate identity")
+	}
+
+	selfSignature := *entity.PrimaryIdentity().SelfSignature
+	selfSignature.IsPrimaryId = nil
+	selfSignature.CreationTime = cfg.Now()
+	if err := selfSignature.SignUserId(uid.Id, entity.PrimaryKey, entity.PrivateKey, cfg); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in signing identity")
+	}
+
+	entity.Identities[uid.Id] = &openpgp.Identity{
+		Name:          uid.Id,
+		UserId:        uid,
+		SelfSignature: &selfSignature,
+		Signatures:    []*packet.Signature{&selfSignature},
+	}
+	return nil
+}
+
+// GenerateCertifyOnlyKey generates a key of the given keyType ("rsa" or "x25519")
+// with all the given identities, like GenerateKeyWithIdentities, but whose
+// primary key can only certify. Messages are signed with a signing subkey
+// and encrypted to an encryption subkey instead.
+// If keyType is "rsa", bits is the RSA bitsize of the keys.
+// If keyType is "x25519" bits is unused.
+func GenerateCertifyOnlyKey(identities []*Identity, keyType string, bits int) (*Key, error) {
+	key, err := GenerateKeyWithIdentities(identities, keyType, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &packet.Config{
+		Algorithm:   packet.PubKeyAlgoRSA,
+		RSABits:     bits,
+		Time:        getKeyGenerationTimeGenerator(),
+		DefaultHash: crypto.SHA256,
+	}
+	if keyType == "x25519" {
+		cfg.Algorithm = packet.PubKeyAlgoEdDSA
+	}
+
+	for _, identity := range key.entity.Identities {
+		selfSignature := *identity.SelfSignature
+		selfSignature.FlagSign = false
+		if err := selfSignature.SignUserId(identity.Name, key.entity.PrimaryKey, key.entity.PrivateKey, cfg); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in signing identity")
+		}
+		identity.SelfSignature = &selfSignature
+		identity.Signatures = []*packet.Signature{&selfSignature}
+	}
+
+	if err := key.entity.AddSigningSubkey(cfg); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in generating signing subkey")
+	}
+
+	return key, nil
+}
+
 // --- Operate on key
 
 // Copy creates a deep copy of the key.
@@ -181,6 +295,23 @@ func (key *Key) Unlock(passphrase []byte) (*Key, error) {
 	return unlockedKey, nil
 }
 
+// CheckPassphrase returns true if passphrase unlocks the key. The key is not
+// modified: it is unlocked in a copy, whose private parameters are cleared
+// right away. False is returned if the key is not locked.
+func (key *Key) CheckPassphrase(passphrase []byte) bool {
+	isLocked, err := key.IsLocked()
+	if err != nil || !isLocked {
+		return false
+	}
+
+	unlockedKey, err := key.Unlock(passphrase)
+	if err != nil {
+		return false
+	}
+	unlockedKey.ClearPrivateParams()
+	return true
+}
+
 // --- Export key
 
 func (key *Key) Serialize() ([]byte, error) {
@@ -246,6 +377,39 @@ func (key *Key) GetArmoredPublicKeyWithCustomHeaders(comment, version string) (s
 	return armor.ArmorWithTypeAndCustomHeaders(serialized, constants.PublicKeyHeader, version, comment)
 }
 
+// GenerateRevocation returns an armored revocation certificate for the key,
+// i.e. a key revocation signature of its primary key, with the given reason.
+// The key must be unlocked. It is not revoked: the certificate can be kept
+// until it is needed, and then applied with KeyRing.ApplyRevocation.
+func (key *Key) GenerateRevocation(reason string) (string, error) {
+	if key.entity.PrivateKey == nil || key.entity.PrivateKey.Encrypted {
+		return "", errors.New("gopenpgp: unable to generate revocation, key is not unlocked")
+	}
+
+	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator()}
+	reasonCode := uint8(packet.NoReason)
+	revocation := &packet.Signature{
+		Version:              key.entity.PrimaryKey.Version,
+		CreationTime:         getNow(),
+		SigType:              packet.SigTypeKeyRevocation,
+		PubKeyAlgo:           key.entity.PrimaryKey.PubKeyAlgo,
+		Hash:                 config.Hash(),
+		RevocationReason:     &reasonCode,
+		RevocationReasonText: reason,
+		IssuerKeyId:          &key.entity.PrimaryKey.KeyId,
+	}
+	if err := revocation.RevokeKey(key.entity.PrimaryKey, key.entity.PrivateKey, config); err != nil {
+		return "", errors.Wrap(err, "gopenpgp: error in signing revocation")
+	}
+
+	var outBuf bytes.Buffer
+	if err := revocation.Serialize(&outBuf); err != nil {
+		return "", errors.Wrap(err, "gopenpgp: error in serializing revocation")
+	}
+
+	return armor.ArmorWithType(outBuf.Bytes(), constants.PublicKeyHeader)
+}
+
 // GetPublicKey returns the unarmored public keys from this keyring.
 func (key *Key) GetPublicKey() (b []byte, err error) {
 	var outBuf bytes.Buffer
@@ -265,11 +429,20 @@ func (key *Key) CanVerify() bool {
 }
 
 // CanEncrypt returns true if any of the subkeys can be used for encryption.
+// A revoked key cannot be used for encryption.
 func (key *Key) CanEncrypt() bool {
+	if key.IsRevoked() {
+		return false
+	}
 	_, canEncrypt := key.entity.EncryptionKey(getNow())
 	return canEncrypt
 }
 
+// IsRevoked returns true if the key has a valid revocation signature.
+func (key *Key) IsRevoked() bool {
+	return len(key.entity.Revocations) > 0
+}
+
 // IsExpired checks whether the key is expired.
 func (key *Key) IsExpired() bool {
 	_, ok := key.entity.EncryptionKey(getNow())
@@ -399,13 +572,26 @@ func getSHA256FingerprintBytes(pk *packet.PublicKey) []byte {
 
 // readFrom reads unarmored and armored keys from r and adds them to the keyring.
 func (key *Key) readFrom(r io.Reader, armored bool) error {
-	var err error
-	var entities openpgp.EntityList
 	if armored {
-		entities, err = openpgp.ReadArmoredKeyRing(r)
-	} else {
-		entities, err = openpgp.ReadKeyRing(r)
+		block, err := pgpArmor.Decode(r)
+		if err == io.EOF {
+			return ErrNoArmoredKey
+		}
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: error in unarmoring key")
+		}
+		if block.Type != openpgp.PublicKeyType && block.Type != openpgp.PrivateKeyType {
+			return fmt.Errorf("%w, got: %s", ErrNoArmoredKey, block.Type)
+		}
+		r = block.Body
+	}
+
+	packets := bufio.NewReader(r)
+	if version, ok := peekKeyVersion(packets); ok && version != 4 && version != 5 {
+		return fmt.Errorf("%w %d", ErrUnsupportedKeyVersion, version)
 	}
+
+	entities, err := openpgp.ReadKeyRing(packets)
 	if err != nil {
 		return errors.Wrap(err, "gopenpgp: error in reading key ring")
 	}
@@ -422,6 +608,75 @@ func (key *Key) readFrom(r io.Reader, armored bool) error {
 	return nil
 }
 
+// peekKeyVersion returns the version of the key packet at the start of r,
+// without consuming it. It returns false if r does not start with a public
+// or secret key packet.
+func peekKeyVersion(r *bufio.Reader) (int, bool) {
+	// The packet tag, a length of up to 5 octets, and the version octet,
+	// see RFC 4880, sections 4.2 and 5.5.2.
+	header, _ := r.Peek(7)
+	if len(header) < 2 || header[0]&0x80 == 0 {
+		return 0, false
+	}
+
+	var tag byte
+	var offset int
+	if header[0]&0x40 == 0 {
+		// Old format packet
+		tag = (header[0] & 0x3f) >> 2
+		if lengthType := header[0] & 3; lengthType == 3 {
+			offset = 1
+		} else {
+			offset = 1 + 1<<lengthType
+		}
+	} else {
+		// New format packet, key packets cannot have partial lengths
+		tag = header[0] & 0x3f
+		switch {
+		case header[1] < 192:
+			offset = 2
+		case header[1] < 224:
+			offset = 3
+		case header[1] == 255:
+			offset = 6
+		default:
+			return 0, false
+		}
+	}
+
+	// Secret key and public key packet tags, see RFC 4880, section 4.3.
+	if (tag != 5 && tag != 6) || len(header) <= offset {
+		return 0, false
+	}
+	return int(header[offset]), true
+}
+
+// normalizeArmoredKey converts the line endings of armored to LF, and trims
+// the whitespace around each line.
+func normalizeArmoredKey(armored string) string {
+	armored = strings.ReplaceAll(armored, "\r\n", "\n")
+	armored = strings.ReplaceAll(armored, "\r", "\n")
+	lines := strings.Split(armored, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findArmoredKeyBlock returns armored from the BEGIN line of its first public
+// or private key block, skipping other armored blocks before it, or false if
+// there is no such line. armored must have been normalized.
+func findArmoredKeyBlock(armored string) (string, bool) {
+	lines := strings.Split(armored, "\n")
+	for i, line := range lines {
+		if line == "-----BEGIN "+constants.PublicKeyHeader+"-----" ||
+			line == "-----BEGIN "+constants.PrivateKeyHeader+"-----" {
+			return strings.Join(lines[i:], "\n"), true
+		}
+	}
+	return "", false
+}
+
 func generateKey(
 	name, email string,
 	keyType string,
@@ -3,6 +3,7 @@ package crypto
 import (
 	"crypto/rsa"
 	"encoding/base64"
+	"errors"
 	"io/ioutil"
 	"regexp"
 	"strings"
@@ -125,6 +126,34 @@ func TestLockUnlockKeys(t *testing.T) {
 	}
 }
 
+func TestCheckPassphrase(t *testing.T) {
+	lockedKey, err := NewKeyFromArmored(keyTestArmoredEC)
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+
+	assert.True(t, lockedKey.CheckPassphrase(keyTestPassphrase))
+	assert.False(t, lockedKey.CheckPassphrase([]byte("wrong passphrase")))
+
+	locked, err := lockedKey.IsLocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking if key is locked, got:", err)
+	}
+	assert.True(t, locked)
+
+	unlockedKey, err := lockedKey.Unlock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	assert.False(t, unlockedKey.CheckPassphrase(keyTestPassphrase))
+
+	publicKey, err := NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+	assert.False(t, publicKey.CheckPassphrase(testMailboxPassword))
+}
+
 func testLockUnlockKey(t *testing.T, armoredKey string, pass []byte) {
 	var err error
 
@@ -435,3 +464,88 @@ func TestKeyCompression(t *testing.T) {
 		keyTestEC.entity.PrimaryIdentity().SelfSignature.PreferredCompression,
 	)
 }
+
+func TestKeyVersions(t *testing.T) {
+	assert.Exactly(t, []int{4, 4, 4}, keyRingTestMultiple.KeyVersions())
+
+	entity, err := openpgp.NewEntity(keyTestName, "", keyTestDomain, &packet.Config{
+		Algorithm: packet.PubKeyAlgoEdDSA,
+		Time:      getTimeGenerator(),
+		V5Keys:    true,
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating v5 key, got:", err)
+	}
+
+	v5Key := &Key{entity}
+	serialized, err := v5Key.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing v5 key, got:", err)
+	}
+
+	importedKey, err := NewKey(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while importing v5 key, got:", err)
+	}
+
+	importedKeyRing, err := NewKeyRing(importedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Exactly(t, []int{5}, importedKeyRing.KeyVersions())
+}
+
+func TestUnsupportedKeyVersion(t *testing.T) {
+	// Sample v6 certificate, see RFC 9580, appendix A.3.
+	armored := readTestFile("key_v6PublicKey", false)
+	_, err := NewKeyFromArmored(armored)
+	assert.True(t, errors.Is(err, ErrUnsupportedKeyVersion))
+	assert.EqualError(t, err, "gopenpgp: unsupported key version 6")
+
+	block, err := armor.Decode(strings.NewReader(armored))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring v6 key, got:", err)
+	}
+	v6PublicKey, err := ioutil.ReadAll(block.Body)
+	if err != nil {
+		t.Fatal("Expected no error while reading v6 key, got:", err)
+	}
+	_, err = NewKey(v6PublicKey)
+	assert.True(t, errors.Is(err, ErrUnsupportedKeyVersion))
+
+	// The same packet with an old format header.
+	oldFormat := append([]byte{0x98}, v6PublicKey[1:]...)
+	_, err = NewKey(oldFormat)
+	assert.True(t, errors.Is(err, ErrUnsupportedKeyVersion))
+}
+
+func TestNewKeyFromArmoredPasted(t *testing.T) {
+	armored := readTestFile("keyring_publicKey", false)
+	pasted := map[string]string{
+		"surrounding text": "Here is my key:\n\n" + armored + "\n\nThanks",
+		"CRLF":             strings.ReplaceAll(armored, "\n", "\r\n"),
+		"CR":               strings.ReplaceAll(armored, "\n", "\r"),
+		"indentation":      "\t" + strings.ReplaceAll(armored, "\n", "\n  "),
+		"trailing spaces":  strings.ReplaceAll(armored, "\n", " \n") + "  ",
+	}
+	for name, input := range pasted {
+		key, err := NewKeyFromArmored(input)
+		if err != nil {
+			t.Fatal("Expected no error while reading key with "+name+", got:", err)
+		}
+		assert.Exactly(t, keyRingTestPublic.GetKeys()[0].GetFingerprint(), key.GetFingerprint())
+	}
+
+	_, err := NewKeyFromArmored("no key here")
+	assert.True(t, errors.Is(err, ErrNoArmoredKey))
+
+	_, err = NewKeyFromArmored(readTestFile("message_signed", false))
+	assert.True(t, errors.Is(err, ErrNoArmoredKey))
+
+	// A key block pasted after another armored block.
+	key, err := NewKeyFromArmored(readTestFile("message_signed", false) + "\n" + armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading key after a message, got:", err)
+	}
+	assert.Exactly(t, keyRingTestPublic.GetKeys()[0].GetFingerprint(), key.GetFingerprint())
+}
@@ -2,9 +2,16 @@ package crypto
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/pkg/errors"
 )
@@ -18,6 +25,19 @@ type KeyRing struct {
 	FirstKeyID string
 }
 
+// Errors returned by keyring operations, to be checked with errors.Is.
+var (
+	// ErrKeyringNotUnlocked is returned when an operation needs an unlocked
+	// private key that the keyring does not have.
+	ErrKeyringNotUnlocked = errors.New("gopenpgp: keyring is not unlocked")
+	// ErrNoKeyInKeyring is returned when an operation needs a key and the
+	// keyring is empty, e.g. by FirstKey, ExpiresWithin and EncryptSessionKey.
+	ErrNoKeyInKeyring = errors.New("gopenpgp: no key available in this keyring")
+	// ErrAllKeysExpired is returned by FilterExpiredKeys when all the keys
+	// are expired.
+	ErrAllKeysExpired = errors.New("gopenpgp: all contacts keys are expired")
+)
+
 // Identity contains the name and the email of a key holder.
 type Identity struct {
 	Name  string
@@ -36,6 +56,125 @@ func NewKeyRing(key *Key) (*KeyRing, error) {
 	return keyRing, err
 }
 
+// NewKeyRingWithPassphrases creates a new KeyRing from keys, unlocking each
+// locked key with the passphrase mapped to its hex fingerprint.
+// Public keys and unlocked keys are added as they are. Since a KeyRing cannot
+// hold locked keys, the locked keys that have no passphrase are not added, and
+// are returned instead. The given keys are never modified.
+// An error is returned if a passphrase is wrong.
+func NewKeyRingWithPassphrases(
+	keys []*Key, passphrases map[string][]byte,
+) (keyRing *KeyRing, lockedKeys []*Key, err error) {
+	keyRing = &KeyRing{}
+	for _, key := range keys {
+		if key.IsPrivate() {
+			locked, err := key.IsLocked()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if locked {
+				passphrase, ok := passphrases[key.GetFingerprint()]
+				if !ok {
+					lockedKeys = append(lockedKeys, key)
+					continue
+				}
+				unlockedKey, err := key.Unlock(passphrase)
+				if err != nil {
+					return nil, nil, errors.Wrap(err, "gopenpgp: unable to unlock key "+key.GetFingerprint())
+				}
+				key = unlockedKey
+			}
+		}
+
+		if err = keyRing.AddKey(key); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return keyRing, lockedKeys, nil
+}
+
+// ScanKeyRing reads the armored or binary keys from r one at a time, and
+// returns a KeyRing containing only the keys whose fingerprint satisfies match.
+// The other keys are discarded as soon as they are read, so that the memory
+// used is bounded by the size of the matching keys. r is read sequentially.
+// Unsupported and malformed keys are skipped.
+func ScanKeyRing(r io.Reader, match func(fingerprint string) bool) (*KeyRing, error) {
+	reader, err := unarmorIfArmored(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to unarmor key ring")
+	}
+
+	keyRing := &KeyRing{}
+	packets := packet.NewReader(reader)
+	for {
+		entity, err := openpgp.ReadEntity(packets)
+		if err != nil {
+			var unsupported pgpErrors.UnsupportedError
+			var structural pgpErrors.StructuralError
+			if errors.As(err, &unsupported) || errors.As(err, &structural) {
+				err = skipToNextEntity(packets)
+			}
+			if errors.Is(err, io.EOF) {
+				return keyRing, nil
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "gopenpgp: error in reading key ring")
+			}
+			continue
+		}
+
+		key := &Key{entity}
+		if !match(key.GetFingerprint()) {
+			continue
+		}
+		if err := keyRing.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ReadLengthPrefixedKeyRings reads binary key records from r until EOF, and
+// returns one KeyRing per record. Each record is the length of the keys as a
+// 4-byte big-endian integer, followed by the binary keys.
+// Private keys must be unlocked, as in AddKey.
+// An error mentioning the index of the record is returned if a record is
+// truncated or cannot be read.
+func ReadLengthPrefixedKeyRings(r io.Reader) ([]*KeyRing, error) {
+	var keyRings []*KeyRing
+	for i := 0; ; i++ {
+		var length [4]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return keyRings, nil
+			}
+			return nil, errors.Wrapf(err, "gopenpgp: unable to read length of key record %d", i)
+		}
+
+		size := binary.BigEndian.Uint32(length[:])
+		data, err := ioutil.ReadAll(io.LimitReader(r, int64(size)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "gopenpgp: unable to read key record %d", i)
+		}
+		if len(data) != int(size) {
+			return nil, errors.Errorf("gopenpgp: key record %d is truncated", i)
+		}
+
+		entities, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrapf(err, "gopenpgp: unable to parse key record %d", i)
+		}
+		keyRing := &KeyRing{}
+		for _, entity := range entities {
+			if err := keyRing.AddKey(&Key{entity}); err != nil {
+				return nil, errors.Wrapf(err, "gopenpgp: unable to add key of record %d", i)
+			}
+		}
+		keyRings = append(keyRings, keyRing)
+	}
+}
+
 // AddKey adds the given key to the keyring.
 func (keyRing *KeyRing) AddKey(key *Key) error {
 	if key.IsPrivate() {
@@ -49,6 +188,93 @@ func (keyRing *KeyRing) AddKey(key *Key) error {
 	return nil
 }
 
+// Merge adds copies of the keys of other to the keyring. A key whose
+// fingerprint is already in the keyring is not added again: its identities and
+// subkeys that are missing from the existing key are merged into it instead,
+// if their self-signature or binding signature is valid.
+// Public subkeys are not merged into private keys, and only the public part
+// of private subkeys is merged into public keys.
+func (keyRing *KeyRing) Merge(other *KeyRing) error {
+	for _, e := range other.entities {
+		var existing *openpgp.Entity
+		for _, known := range keyRing.entities {
+			if bytes.Equal(known.PrimaryKey.Fingerprint, e.PrimaryKey.Fingerprint) {
+				existing = known
+				break
+			}
+		}
+		if existing == nil {
+			copied, err := copyEntity(e)
+			if err != nil {
+				return err
+			}
+			keyRing.entities = append(keyRing.entities, copied)
+			continue
+		}
+
+		if err := mergeEntity(existing, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeEntity adds copies of the valid identities and subkeys of e that are
+// missing from existing, which has the same primary key, to existing.
+func mergeEntity(existing, e *openpgp.Entity) error {
+	// The new identities and subkeys are copied along with the primary
+	// identity, as a key needs an identity to be read back
+	primary := existing.PrimaryIdentity()
+	missing := &openpgp.Entity{
+		PrimaryKey: existing.PrimaryKey,
+		PrivateKey: existing.PrivateKey,
+		Identities: map[string]*openpgp.Identity{primary.Name: primary},
+	}
+
+	for name, identity := range e.Identities {
+		if _, ok := existing.Identities[name]; ok || identity.SelfSignature == nil {
+			continue
+		}
+		if existing.PrimaryKey.VerifyUserIdSignature(name, existing.PrimaryKey, identity.SelfSignature) != nil {
+			continue
+		}
+		missing.Identities[name] = identity
+	}
+
+	for _, subkey := range e.Subkeys {
+		if existing.PrivateKey != nil && subkey.PrivateKey == nil {
+			continue
+		}
+		isKnown := false
+		for _, knownSubkey := range existing.Subkeys {
+			if bytes.Equal(knownSubkey.PublicKey.Fingerprint, subkey.PublicKey.Fingerprint) {
+				isKnown = true
+				break
+			}
+		}
+		if isKnown || subkey.Sig == nil || existing.PrimaryKey.VerifyKeySignature(subkey.PublicKey, subkey.Sig) != nil {
+			continue
+		}
+		missing.Subkeys = append(missing.Subkeys, subkey)
+	}
+
+	if len(missing.Identities) == 1 && len(missing.Subkeys) == 0 {
+		return nil
+	}
+
+	copied, err := copyEntity(missing)
+	if err != nil {
+		return err
+	}
+	for name, identity := range copied.Identities {
+		if name != primary.Name {
+			existing.Identities[name] = identity
+		}
+	}
+	existing.Subkeys = append(existing.Subkeys, copied.Subkeys...)
+	return nil
+}
+
 // --- Extract keys from keyring
 
 // GetKeys returns openpgp keys contained in this KeyRing.
@@ -82,12 +308,75 @@ func (keyRing *KeyRing) getSigningEntity() (*openpgp.Entity, error) {
 		}
 	}
 	if signEntity == nil {
-		return nil, errors.New("gopenpgp: cannot sign message, unable to unlock signer key")
+		return nil, ErrKeyringNotUnlocked
 	}
 
 	return signEntity, nil
 }
 
+// getSigningEntityByKeyID returns the entity of the keyring holding the
+// unlocked private key, or subkey, with the given key ID.
+func (keyRing *KeyRing) getSigningEntityByKeyID(keyID uint64) (*openpgp.Entity, error) {
+	for _, key := range keyRing.entities.KeysById(keyID) {
+		if key.PrivateKey == nil || key.PrivateKey.Encrypted {
+			return nil, ErrKeyringNotUnlocked
+		}
+		return key.Entity, nil
+	}
+
+	return nil, errors.New("gopenpgp: cannot sign message, signer key not found in keyring")
+}
+
+// GetArmoredPublicKeys returns the public keys of the keyring armored
+// separately, one armor block per key, separated by an empty line.
+func (keyRing *KeyRing) GetArmoredPublicKeys() (string, error) {
+	armoredKeys := make([]string, len(keyRing.entities))
+	for i, e := range keyRing.entities {
+		armored, err := (&Key{e}).GetArmoredPublicKey()
+		if err != nil {
+			return "", err
+		}
+		armoredKeys[i] = armored
+	}
+
+	return strings.Join(armoredKeys, "\n\n"), nil
+}
+
+// GetArmoredPublicKeysWithCustomHeaders returns the public keys of the keyring
+// armored separately like GetArmoredPublicKeys, with the given headers.
+// Empty parameters are omitted from the headers.
+func (keyRing *KeyRing) GetArmoredPublicKeysWithCustomHeaders(comment, version string) (string, error) {
+	armoredKeys := make([]string, len(keyRing.entities))
+	for i, e := range keyRing.entities {
+		armored, err := (&Key{e}).GetArmoredPublicKeyWithCustomHeaders(comment, version)
+		if err != nil {
+			return "", err
+		}
+		armoredKeys[i] = armored
+	}
+
+	return strings.Join(armoredKeys, "\n\n"), nil
+}
+
+// GetArmoredPrivateKey returns the armored private key of the key of the
+// keyring that has the given key ID, as a primary key or a subkey, e.g. to
+// back up a single key. Since keyrings only hold unlocked keys, the private
+// key material is not encrypted: lock the key with GetKey and Key.Lock to
+// export it with a passphrase.
+// An error is returned if the key is not in the keyring or is not private.
+func (keyRing *KeyRing) GetArmoredPrivateKey(keyID uint64) (string, error) {
+	keys := keyRing.entities.KeysById(keyID)
+	if len(keys) == 0 {
+		return "", errors.New("gopenpgp: key not found in keyring")
+	}
+	key := &Key{keys[0].Entity}
+	if !key.IsPrivate() {
+		return "", errors.New("gopenpgp: key is not a private key")
+	}
+
+	return key.Armor()
+}
+
 // --- Extract info from key
 
 // CountEntities returns the number of entities in the keyring.
@@ -114,6 +403,216 @@ func (keyRing *KeyRing) GetIdentities() []*Identity {
 	return identities
 }
 
+// GetPrimaryIdentity returns the primary identity of the first key of the
+// keyring, i.e. the identity whose self-signature marks it as primary, or nil
+// if the keyring is empty.
+// If several identities are marked as primary, the most recently signed one
+// is returned. If none is, the first signed one is returned, so that the
+// result does not depend on the order of the identities in memory.
+func (keyRing *KeyRing) GetPrimaryIdentity() *Identity {
+	if len(keyRing.entities) == 0 {
+		return nil
+	}
+
+	primary := primaryIdentity(keyRing.entities[0])
+	if primary == nil {
+		return nil
+	}
+
+	return &Identity{
+		Name:  primary.UserId.Name,
+		Email: primary.UserId.Email,
+	}
+}
+
+// primaryIdentity returns the primary identity of an entity, chosen like in
+// GetPrimaryIdentity, or nil if it has no identity.
+func primaryIdentity(e *openpgp.Entity) *openpgp.Identity {
+	var primary *openpgp.Identity
+	for _, id := range e.Identities {
+		if primary == nil || isPreferredPrimaryIdentity(id, primary) {
+			primary = id
+		}
+	}
+	return primary
+}
+
+// isPreferredPrimaryIdentity returns true if candidate should be returned by
+// GetPrimaryIdentity instead of current.
+func isPreferredPrimaryIdentity(candidate, current *openpgp.Identity) bool {
+	candidateSig, currentSig := candidate.SelfSignature, current.SelfSignature
+	isCandidateMarked := candidateSig.IsPrimaryId != nil && *candidateSig.IsPrimaryId
+	isCurrentMarked := currentSig.IsPrimaryId != nil && *currentSig.IsPrimaryId
+
+	switch {
+	case isCandidateMarked != isCurrentMarked:
+		return isCandidateMarked
+	case !candidateSig.CreationTime.Equal(currentSig.CreationTime):
+		return candidateSig.CreationTime.After(currentSig.CreationTime) == isCandidateMarked
+	default:
+		return candidate.Name < current.Name
+	}
+}
+
+// ErrKeyRevoked is returned when encrypting to a keyring containing a revoked key.
+var ErrKeyRevoked = errors.New("gopenpgp: cannot encrypt to a revoked key")
+
+// ApplyRevocation adds the key revocation signatures of an armored or binary
+// revocation certificate, as generated by Key.GenerateRevocation, to the
+// matching keys of the keyring.
+// Encrypting to a revoked key returns ErrKeyRevoked, and the signatures made
+// by a revoked key are not valid anymore.
+// Revocations are not included when the keys are serialized, so the
+// certificate must be applied again after reading the keys.
+// An error is returned if no signature of the certificate is a valid
+// revocation of a key of the keyring.
+func (keyRing *KeyRing) ApplyRevocation(revocation string) error {
+	reader, err := unarmorIfArmored(strings.NewReader(revocation))
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to unarmor revocation")
+	}
+
+	applied := false
+	packets := packet.NewReader(reader)
+	for {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: error in reading revocation")
+		}
+
+		sig, ok := p.(*packet.Signature)
+		if !ok || sig.SigType != packet.SigTypeKeyRevocation {
+			continue
+		}
+		for _, e := range keyRing.entities {
+			if e.PrimaryKey.VerifyRevocationSignature(sig) == nil {
+				e.Revocations = append(e.Revocations, sig)
+				applied = true
+			}
+		}
+	}
+
+	if !applied {
+		return errors.New("gopenpgp: no valid revocation for the keys of the keyring")
+	}
+	return nil
+}
+
+// checkNotRevoked returns ErrKeyRevoked if a key of the keyring is revoked.
+func (keyRing *KeyRing) checkNotRevoked() error {
+	for _, e := range keyRing.entities {
+		if len(e.Revocations) > 0 {
+			return ErrKeyRevoked
+		}
+	}
+	return nil
+}
+
+// AddIdentity adds an identity with the given name and email to the first key
+// of the keyring, signed by its primary key. The new identity shares the
+// preferences, flags and expiration of the primary identity, which stays
+// primary. Keys in a keyring are always unlocked, so no passphrase is needed.
+func (keyRing *KeyRing) AddIdentity(name, email string) error {
+	if len(keyRing.entities) == 0 {
+		return errors.New("gopenpgp: cannot add an identity to an empty keyring")
+	}
+	entity := keyRing.entities[0]
+	if entity.PrivateKey == nil {
+		return errors.New("gopenpgp: a private key is needed to sign a new identity")
+	}
+
+	cfg := &packet.Config{Time: getKeyGenerationTimeGenerator()}
+	return addIdentity(entity, &Identity{Name: name, Email: email}, cfg)
+}
+
+// ErrLastIdentity is returned by RemoveIdentity when removing identities
+// would leave a key without any identity.
+var ErrLastIdentity = errors.New("gopenpgp: cannot remove the last identity of a key")
+
+// RemoveIdentity removes the identities with the given email, and their
+// self-signatures, from all the keys of the keyring.
+// If the primary identity of a key is removed, the remaining identity with the
+// lowest user ID is signed again as the primary one, which requires the key to
+// be private. Keys in a keyring are always unlocked, so no passphrase is needed.
+// An error is returned, and the keyring is left unchanged, if a key
+// would be left without any identity, or if the primary identity of a public
+// key would be removed.
+func (keyRing *KeyRing) RemoveIdentity(email string) error {
+	for _, e := range keyRing.entities {
+		remaining := 0
+		for _, id := range e.Identities {
+			if !strings.EqualFold(id.UserId.Email, email) {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			return ErrLastIdentity
+		}
+		if e.PrivateKey == nil && strings.EqualFold(e.PrimaryIdentity().UserId.Email, email) {
+			return errors.New("gopenpgp: a private key is needed to sign a new primary identity")
+		}
+	}
+
+	// Sign all the new primary identities first, so that the keyring is
+	// left unchanged if any signature fails
+	cfg := &packet.Config{Time: getKeyGenerationTimeGenerator()}
+	newPrimaries := make(map[*openpgp.Entity]*openpgp.Identity)
+	newSelfSignatures := make(map[*openpgp.Entity]*packet.Signature)
+	for _, e := range keyRing.entities {
+		if !strings.EqualFold(e.PrimaryIdentity().UserId.Email, email) {
+			continue
+		}
+
+		names := make([]string, 0, len(e.Identities))
+		for name, id := range e.Identities {
+			if !strings.EqualFold(id.UserId.Email, email) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		newPrimary := e.Identities[names[0]]
+
+		isPrimaryID := true
+		selfSignature := *newPrimary.SelfSignature
+		selfSignature.IsPrimaryId = &isPrimaryID
+		selfSignature.CreationTime = cfg.Now()
+		if err := selfSignature.SignUserId(newPrimary.Name, e.PrimaryKey, e.PrivateKey, cfg); err != nil {
+			return errors.Wrap(err, "gopenpgp: error in signing identity")
+		}
+		newPrimaries[e] = newPrimary
+		newSelfSignatures[e] = &selfSignature
+	}
+
+	for _, e := range keyRing.entities {
+		for name, id := range e.Identities {
+			if strings.EqualFold(id.UserId.Email, email) {
+				delete(e.Identities, name)
+			}
+		}
+
+		newPrimary, ok := newPrimaries[e]
+		if !ok {
+			continue
+		}
+		selfSignature := newSelfSignatures[e]
+		replaced := false
+		for i, sig := range newPrimary.Signatures {
+			if sig == newPrimary.SelfSignature {
+				newPrimary.Signatures[i] = selfSignature
+				replaced = true
+			}
+		}
+		if !replaced {
+			newPrimary.Signatures = append(newPrimary.Signatures, selfSignature)
+		}
+		newPrimary.SelfSignature = selfSignature
+	}
+	return nil
+}
+
 // CanVerify returns true if any of the keys in the keyring can be used for verification.
 func (keyRing *KeyRing) CanVerify() bool {
 	keys := keyRing.GetKeys()
@@ -136,6 +635,37 @@ func (keyRing *KeyRing) CanEncrypt() bool {
 	return false
 }
 
+// CanSign returns true if the keyring can be used to sign messages, i.e. if
+// the first unlocked private key of the keyring, which is the one used for
+// signing, has a key or subkey that can sign.
+func (keyRing *KeyRing) CanSign() bool {
+	signEntity, err := keyRing.getSigningEntity()
+	if err != nil {
+		return false
+	}
+	signingKey, ok := signEntity.SigningKey(getNow())
+	return ok && signingKey.PrivateKey != nil && !signingKey.PrivateKey.Encrypted
+}
+
+// IsFullyUnlocked returns true if the keyring contains private keys, and all
+// of them are unlocked.
+// AddKey refuses locked keys, so all the private keys of a keyring are always
+// unlocked, and this only returns false for keyrings with public keys only,
+// e.g. to check that a keyring can sign or decrypt before a long operation.
+func (keyRing *KeyRing) IsFullyUnlocked() bool {
+	hasPrivateKey := false
+	for _, key := range keyRing.GetKeys() {
+		if !key.IsPrivate() {
+			continue
+		}
+		hasPrivateKey = true
+		if unlocked, err := key.IsUnlocked(); err != nil || !unlocked {
+			return false
+		}
+	}
+	return hasPrivateKey
+}
+
 // GetKeyIDs returns array of IDs of keys in this KeyRing.
 func (keyRing *KeyRing) GetKeyIDs() []uint64 {
 	var res = make([]uint64, len(keyRing.entities))
@@ -145,6 +675,206 @@ func (keyRing *KeyRing) GetKeyIDs() []uint64 {
 	return res
 }
 
+// GetFingerprints returns the hex fingerprints of the primary keys in this
+// KeyRing, as Key.GetFingerprint.
+func (keyRing *KeyRing) GetFingerprints() []string {
+	var res = make([]string, len(keyRing.entities))
+	for id, e := range keyRing.entities {
+		res[id] = hex.EncodeToString(e.PrimaryKey.Fingerprint)
+	}
+	return res
+}
+
+// KeyIDFormats contains the common display formats of the identifiers of a
+// primary key.
+type KeyIDFormats struct {
+	// ShortKeyID is the last 8 hex digits of the key ID.
+	ShortKeyID string
+	// LongKeyID is the 16 hex digits of the key ID.
+	LongKeyID string
+	// Fingerprint is the upper case hex fingerprint, split into groups of
+	// four digits separated by spaces, like in GnuPG.
+	Fingerprint string
+	// SHA256Fingerprint is the hex SHA-256 fingerprint, as
+	// Key.GetSHA256Fingerprints.
+	SHA256Fingerprint string
+}
+
+// KeyIDStrings returns the identifiers of the primary key of each key in this
+// KeyRing, in every display format.
+func (keyRing *KeyRing) KeyIDStrings() []KeyIDFormats {
+	var res = make([]KeyIDFormats, len(keyRing.entities))
+	for id, e := range keyRing.entities {
+		longKeyID := keyIDToHex(e.PrimaryKey.KeyId)
+		res[id] = KeyIDFormats{
+			ShortKeyID:        longKeyID[len(longKeyID)-8:],
+			LongKeyID:         longKeyID,
+			Fingerprint:       formatFingerprint(e.PrimaryKey.Fingerprint),
+			SHA256Fingerprint: hex.EncodeToString(getSHA256FingerprintBytes(e.PrimaryKey)),
+		}
+	}
+	return res
+}
+
+// formatFingerprint formats a fingerprint in upper case hex digits, in groups
+// of four separated by spaces.
+func formatFingerprint(fingerprint []byte) string {
+	digits := strings.ToUpper(hex.EncodeToString(fingerprint))
+	groups := make([]string, 0, (len(digits)+3)/4)
+	for len(digits) > 4 {
+		groups = append(groups, digits[:4])
+		digits = digits[4:]
+	}
+	return strings.Join(append(groups, digits), " ")
+}
+
+// Contains returns true if a primary key or a subkey of this KeyRing has the
+// given key ID.
+func (keyRing *KeyRing) Contains(keyID uint64) bool {
+	return len(keyRing.entities.KeysById(keyID)) > 0
+}
+
+// ContainsFingerprint returns true if a primary key or a subkey of this
+// KeyRing has the given hex fingerprint. The comparison is case insensitive.
+func (keyRing *KeyRing) ContainsFingerprint(fingerprint string) bool {
+	for _, e := range keyRing.entities {
+		if strings.EqualFold(hex.EncodeToString(e.PrimaryKey.Fingerprint), fingerprint) {
+			return true
+		}
+		for _, subkey := range e.Subkeys {
+			if strings.EqualFold(hex.EncodeToString(subkey.PublicKey.Fingerprint), fingerprint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// KeyVersions returns the version of the primary key of each key in this KeyRing.
+func (keyRing *KeyRing) KeyVersions() []int {
+	var res = make([]int, len(keyRing.entities))
+	for id, e := range keyRing.entities {
+		res[id] = e.PrimaryKey.Version
+	}
+	return res
+}
+
+// ExpiresWithin returns true if any key in use in the keyring expires within
+// the given duration of the given time. Keys that are already expired or
+// revoked at that time are not considered expiring.
+func (keyRing *KeyRing) ExpiresWithin(d time.Duration, at time.Time) (bool, error) {
+	if len(keyRing.entities) == 0 {
+		return false, ErrNoKeyInKeyring
+	}
+
+	if d < 0 {
+		return false, errors.New("gopenpgp: negative duration")
+	}
+
+	for _, e := range keyRing.entities {
+		if len(e.Revocations) > 0 {
+			continue
+		}
+
+		primarySig := e.PrimaryIdentity().SelfSignature
+		if e.PrimaryKey.KeyExpired(primarySig, at) {
+			continue
+		}
+
+		if keyExpiresBefore(e.PrimaryKey, primarySig, at.Add(d)) {
+			return true, nil
+		}
+
+		for _, subkey := range e.Subkeys {
+			if subkey.Sig.SigType == packet.SigTypeSubkeyRevocation || subkey.PublicKey.KeyExpired(subkey.Sig, at) {
+				continue
+			}
+
+			if keyExpiresBefore(subkey.PublicKey, subkey.Sig, at.Add(d)) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// IsExpired returns true if none of the keys in the keyring can be used for
+// encryption anymore, as Key.IsExpired.
+func (keyRing *KeyRing) IsExpired() bool {
+	now := getNow()
+	for _, e := range keyRing.entities {
+		if _, ok := e.EncryptionKey(now); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// EarliestExpiration returns the earliest expiration time of the keys and
+// subkeys of the keyring, including the ones already expired.
+// Revoked subkeys are ignored. The returned bool is false if none of the keys
+// expires.
+func (keyRing *KeyRing) EarliestExpiration() (time.Time, bool) {
+	var earliest time.Time
+	found := false
+	update := func(publicKey *packet.PublicKey, sig *packet.Signature) {
+		if expiry, ok := keyExpiration(publicKey, sig); ok && (!found || expiry.Before(earliest)) {
+			earliest, found = expiry, true
+		}
+	}
+
+	for _, e := range keyRing.entities {
+		update(e.PrimaryKey, e.PrimaryIdentity().SelfSignature)
+		for _, subkey := range e.Subkeys {
+			if subkey.Sig.SigType != packet.SigTypeSubkeyRevocation {
+				update(subkey.PublicKey, subkey.Sig)
+			}
+		}
+	}
+	return earliest, found
+}
+
+// HasDuplicateSubkeys returns true if any key in the keyring contains
+// several subkeys with the same fingerprint.
+func (keyRing *KeyRing) HasDuplicateSubkeys() bool {
+	for _, e := range keyRing.entities {
+		fingerprints := make(map[string]bool, len(e.Subkeys))
+		for _, subkey := range e.Subkeys {
+			fingerprint := string(subkey.PublicKey.Fingerprint)
+			if fingerprints[fingerprint] {
+				return true
+			}
+			fingerprints[fingerprint] = true
+		}
+	}
+	return false
+}
+
+// DedupSubkeys removes the duplicate subkeys, i.e. with the same fingerprint,
+// from the keys of the keyring. Of each set of duplicates, the subkey with a
+// revocation signature is kept if any, otherwise the subkey with the most
+// recent binding signature.
+func (keyRing *KeyRing) DedupSubkeys() {
+	for _, e := range keyRing.entities {
+		indexes := make(map[string]int, len(e.Subkeys))
+		subkeys := make([]openpgp.Subkey, 0, len(e.Subkeys))
+		for _, subkey := range e.Subkeys {
+			fingerprint := string(subkey.PublicKey.Fingerprint)
+			i, ok := indexes[fingerprint]
+			if !ok {
+				indexes[fingerprint] = len(subkeys)
+				subkeys = append(subkeys, subkey)
+				continue
+			}
+			if shouldReplaceSubkey(subkeys[i].Sig, subkey.Sig) {
+				subkeys[i] = subkey
+			}
+		}
+		e.Subkeys = subkeys
+	}
+}
+
 // --- Filter keyrings
 
 // FilterExpiredKeys takes a given KeyRing list and it returns only those
@@ -187,7 +917,7 @@ func FilterExpiredKeys(contactKeys []*KeyRing) (filteredKeys []*KeyRing, err err
 	}
 
 	if len(filteredKeys) == 0 && hasExpiredEntity {
-		return filteredKeys, errors.New("gopenpgp: all contacts keys are expired")
+		return filteredKeys, ErrAllKeysExpired
 	}
 
 	return filteredKeys, nil
@@ -196,7 +926,7 @@ func FilterExpiredKeys(contactKeys []*KeyRing) (filteredKeys []*KeyRing, err err
 // FirstKey returns a KeyRing with only the first key of the original one.
 func (keyRing *KeyRing) FirstKey() (*KeyRing, error) {
 	if len(keyRing.entities) == 0 {
-		return nil, errors.New("gopenpgp: No key available in this keyring")
+		return nil, ErrNoKeyInKeyring
 	}
 	newKeyRing := &KeyRing{}
 	newKeyRing.entities = keyRing.entities[:1]
@@ -204,36 +934,88 @@ func (keyRing *KeyRing) FirstKey() (*KeyRing, error) {
 	return newKeyRing.Copy()
 }
 
+// ToPublic returns a new keyring containing only the public keys of the
+// keyring, without any private key material. Public keys are copied as is.
+func (keyRing *KeyRing) ToPublic() (*KeyRing, error) {
+	publicKeyRing := &KeyRing{FirstKeyID: keyRing.FirstKeyID}
+
+	for _, entity := range keyRing.entities {
+		var buffer bytes.Buffer
+		if err := entity.Serialize(&buffer); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in serializing public key")
+		}
+
+		publicEntity, err := openpgp.ReadEntity(packet.NewReader(&buffer))
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in reading public key")
+		}
+		publicKeyRing.appendKey(&Key{publicEntity})
+	}
+
+	return publicKeyRing, nil
+}
+
 // Copy creates a deep copy of the keyring.
 func (keyRing *KeyRing) Copy() (*KeyRing, error) {
 	newKeyRing := &KeyRing{}
 
 	entities := make([]*openpgp.Entity, len(keyRing.entities))
 	for id, entity := range keyRing.entities {
-		var buffer bytes.Buffer
 		var err error
-
-		if entity.PrivateKey == nil {
-			err = entity.Serialize(&buffer)
-		} else {
-			err = entity.SerializePrivateWithoutSigning(&buffer, nil)
+		if entities[id], err = copyEntity(entity); err != nil {
+			return nil, err
 		}
+	}
+	newKeyRing.entities = entities
+	newKeyRing.FirstKeyID = keyRing.FirstKeyID
 
-		if err != nil {
-			return nil, errors.Wrap(err, "gopenpgp: unable to copy key: error in serializing entity")
-		}
+	return newKeyRing, nil
+}
 
-		bt := buffer.Bytes()
-		entities[id], err = openpgp.ReadEntity(packet.NewReader(bytes.NewReader(bt)))
+// copyEntity creates a deep copy of an entity, by serializing it and reading
+// it back.
+func copyEntity(entity *openpgp.Entity) (*openpgp.Entity, error) {
+	var buffer bytes.Buffer
+	var err error
+
+	if entity.PrivateKey == nil {
+		err = entity.Serialize(&buffer)
+	} else {
+		err = entity.SerializePrivateWithoutSigning(&buffer, nil)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to copy key: error in serializing entity")
+	}
 
+	copied, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(buffer.Bytes())))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to copy key: error in reading entity")
+	}
+	return copied, nil
+}
+
+// Lock returns locked copies of the keys of the keyring, encrypted with
+// passphrase like Key.Lock, and then clears the private parameters of the
+// keyring like ClearPrivateParams, so that the unlocked private keys do not
+// remain in memory. Public keys are returned as copies.
+// The keyring is left unchanged if a key cannot be locked.
+func (keyRing *KeyRing) Lock(passphrase []byte) ([]*Key, error) {
+	lockedKeys := make([]*Key, len(keyRing.entities))
+	for i, key := range keyRing.GetKeys() {
+		var err error
+		if key.IsPrivate() {
+			lockedKeys[i], err = key.Lock(passphrase)
+		} else {
+			lockedKeys[i], err = key.Copy()
+		}
 		if err != nil {
-			return nil, errors.Wrap(err, "gopenpgp: unable to copy key: error in reading entity")
+			return nil, err
 		}
 	}
-	newKeyRing.entities = entities
-	newKeyRing.FirstKeyID = keyRing.FirstKeyID
 
-	return newKeyRing, nil
+	keyRing.ClearPrivateParams()
+	return lockedKeys, nil
 }
 
 func (keyRing *KeyRing) ClearPrivateParams() {
@@ -248,3 +1030,79 @@ func (keyRing *KeyRing) ClearPrivateParams() {
 func (keyRing *KeyRing) appendKey(key *Key) {
 	keyRing.entities = append(keyRing.entities, key.entity)
 }
+
+// sortedByEncryptionKeyID returns a copy of the entity list of the keyring,
+// sorted by the key ID of the current encryption key of each entity.
+func (keyRing *KeyRing) sortedByEncryptionKeyID() openpgp.EntityList {
+	now := getNow()
+	keyIDs := make(map[*openpgp.Entity]uint64, len(keyRing.entities))
+	for _, e := range keyRing.entities {
+		if encryptionKey, ok := e.EncryptionKey(now); ok {
+			keyIDs[e] = encryptionKey.PublicKey.KeyId
+		}
+	}
+
+	sorted := make(openpgp.EntityList, len(keyRing.entities))
+	copy(sorted, keyRing.entities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return keyIDs[sorted[i]] < keyIDs[sorted[j]]
+	})
+	return sorted
+}
+
+// skipToNextEntity reads packets until the start of the next entity, and
+// leaves its first packet in the reader.
+func skipToNextEntity(packets *packet.Reader) error {
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			var unsupported pgpErrors.UnsupportedError
+			if errors.As(err, &unsupported) {
+				continue
+			}
+			return err
+		}
+
+		switch pk := p.(type) {
+		case *packet.PublicKey:
+			if !pk.IsSubkey {
+				packets.Unread(p)
+				return nil
+			}
+		case *packet.PrivateKey:
+			if !pk.IsSubkey {
+				packets.Unread(p)
+				return nil
+			}
+		}
+	}
+}
+
+// keyExpiresBefore returns true if the key bound by the given self-signature
+// has an expiration time that is not after the given deadline.
+func keyExpiresBefore(publicKey *packet.PublicKey, sig *packet.Signature, deadline time.Time) bool {
+	expiry, ok := keyExpiration(publicKey, sig)
+	return ok && !expiry.After(deadline)
+}
+
+// keyExpiration returns the expiration time of the key bound by the given
+// self-signature, and false if it never expires.
+func keyExpiration(publicKey *packet.PublicKey, sig *packet.Signature) (time.Time, bool) {
+	if sig.KeyLifetimeSecs == nil || *sig.KeyLifetimeSecs == 0 {
+		return time.Time{}, false
+	}
+
+	return publicKey.CreationTime.Add(time.Duration(*sig.KeyLifetimeSecs) * time.Second), true
+}
+
+// shouldReplaceSubkey returns true if a duplicate subkey bound by newSig
+// should be kept instead of the one bound by existingSig.
+func shouldReplaceSubkey(existingSig, newSig *packet.Signature) bool {
+	if existingSig.SigType == packet.SigTypeSubkeyRevocation {
+		return false
+	}
+	if newSig.SigType == packet.SigTypeSubkeyRevocation {
+		return true
+	}
+	return newSig.CreationTime.After(existingSig.CreationTime)
+}
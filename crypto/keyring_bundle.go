@@ -0,0 +1,340 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"math/big"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp/ecdh"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// KeyBundleEntry contains the public information of a key, ready to be
+// serialized for public directory consumption. It never contains private
+// key material.
+type KeyBundleEntry struct {
+	ArmoredPublicKey string `json:"armoredPublicKey"`
+	Fingerprint      string `json:"fingerprint"`
+	Algorithm        string `json:"algorithm"`
+	// Identities lists the identities of the key, the primary identity, as
+	// returned by KeyRing.GetPrimaryIdentity, first and the others sorted by user ID.
+	Identities []KeyBundleIdentity `json:"identities"`
+	// Expiration is the unix time at which the primary key expires, 0 if it never expires.
+	Expiration int64 `json:"expiration"`
+}
+
+// KeyBundleIdentity is an identity of a KeyBundleEntry.
+type KeyBundleIdentity struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// ExportBundle returns a KeyBundleEntry for each key in the keyring.
+func (keyRing *KeyRing) ExportBundle() ([]KeyBundleEntry, error) {
+	bundle := make([]KeyBundleEntry, len(keyRing.entities))
+	for i, e := range keyRing.entities {
+		key := &Key{e}
+		armoredPublicKey, err := key.GetArmoredPublicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		primary := primaryIdentity(e)
+		names := make([]string, 0, len(e.Identities))
+		for name, id := range e.Identities {
+			if id != primary {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		identities := []KeyBundleIdentity{{
+			Name:  primary.UserId.Name,
+			Email: primary.UserId.Email,
+		}}
+		for _, name := range names {
+			id := e.Identities[name]
+			identities = append(identities, KeyBundleIdentity{
+				Name:  id.UserId.Name,
+				Email: id.UserId.Email,
+			})
+		}
+
+		var expiration int64
+		if expirationTime, ok := keyExpiration(e.PrimaryKey, primary.SelfSignature); ok {
+			expiration = expirationTime.Unix()
+		}
+
+		bundle[i] = KeyBundleEntry{
+			ArmoredPublicKey: armoredPublicKey,
+			Fingerprint:      key.GetFingerprint(),
+			Algorithm:        getAlgorithmName(e.PrimaryKey.PubKeyAlgo),
+			Identities:       identities,
+			Expiration:       expiration,
+		}
+	}
+
+	return bundle, nil
+}
+
+// PublicKeyParams contains the raw public parameters of a key, to be used by
+// libraries that do not understand OpenPGP.
+// Integers are encoded as unsigned big-endian byte arrays without leading zeros.
+// Points on NIST, Brainpool and secp256k1 curves are encoded in the uncompressed
+// form 0x04 || X || Y, see SEC 1, section 2.3.3. Curve25519 points are the 32
+// bytes u-coordinate defined in RFC 7748, and Ed25519 points are the 32 bytes
+// public key defined in RFC 8032.
+type PublicKeyParams struct {
+	KeyID     uint64
+	Algorithm string
+	// N and E are set for RSA keys.
+	N, E []byte
+	// Curve and Point are set for ECDH, ECDSA and EdDSA keys.
+	Curve string
+	Point []byte
+}
+
+// PublicKeyParameters returns the raw public parameters of every key in the
+// keyring, primary keys and subkeys, in order.
+// An error is returned if a key uses an unsupported algorithm (DSA or ElGamal).
+func (keyRing *KeyRing) PublicKeyParameters() ([]PublicKeyParams, error) {
+	var params []PublicKeyParams
+	for _, e := range keyRing.entities {
+		publicKeys := []*packet.PublicKey{e.PrimaryKey}
+		for _, subKey := range e.Subkeys {
+			publicKeys = append(publicKeys, subKey.PublicKey)
+		}
+
+		for _, publicKey := range publicKeys {
+			keyParams, err := getPublicKeyParams(publicKey)
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, keyParams)
+		}
+	}
+
+	return params, nil
+}
+
+// getPublicKeyParams extracts the raw public parameters of a single key.
+func getPublicKeyParams(publicKey *packet.PublicKey) (PublicKeyParams, error) {
+	publicKey, err := reparsePublicKey(publicKey)
+	if err != nil {
+		return PublicKeyParams{}, err
+	}
+
+	params := PublicKeyParams{
+		KeyID:     publicKey.KeyId,
+		Algorithm: getAlgorithmName(publicKey.PubKeyAlgo),
+	}
+
+	switch pub := publicKey.PublicKey.(type) {
+	case *rsa.PublicKey:
+		params.N = pub.N.Bytes()
+		params.E = big.NewInt(int64(pub.E)).Bytes()
+	case *ecdsa.PublicKey:
+		params.Curve = pub.Curve.Params().Name
+		params.Point = elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+	case *ecdh.PublicKey:
+		if pub.Y == nil {
+			// Curve25519 keys only have the native encoding, prefixed with 0x40.
+			params.Curve = "Curve25519"
+			params.Point = pub.X.Bytes()[1:]
+		} else {
+			params.Curve = pub.Curve.Params().Name
+			params.Point = elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+		}
+	case *ed25519.PublicKey:
+		params.Curve = "Ed25519"
+		params.Point = append([]byte{}, *pub...)
+	default:
+		return PublicKeyParams{}, errors.New("gopenpgp: unsupported public key algorithm: " + params.Algorithm)
+	}
+
+	return params, nil
+}
+
+// reparsePublicKey serializes and parses again a public key.
+// The in-memory form of freshly generated keys differs from the one of
+// parsed keys, e.g. for Curve25519, so the key is parsed again.
+func reparsePublicKey(publicKey *packet.PublicKey) (*packet.PublicKey, error) {
+	var buffer bytes.Buffer
+	if err := publicKey.Serialize(&buffer); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in serializing public key")
+	}
+	p, err := packet.Read(&buffer)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading public key")
+	}
+	parsed, ok := p.(*packet.PublicKey)
+	if !ok {
+		return nil, errors.New("gopenpgp: invalid public key packet")
+	}
+	return parsed, nil
+}
+
+// SubkeyDetails describes the capabilities of a key, or subkey.
+type SubkeyDetails struct {
+	KeyID     uint64
+	IsPrimary bool
+	Algorithm string
+	// BitLength is the size of the key: the size of the modulus for RSA keys,
+	// and the size of the curve for ECC keys. It is 0 if it is unknown.
+	BitLength int
+	// CreationTime is the unix time at which the key was created.
+	CreationTime int64
+	// Expiration is the unix time at which the key expires, 0 if it never expires.
+	Expiration int64
+	CanEncrypt bool
+	CanSign    bool
+}
+
+// SubkeyInfo returns the details of every key in the keyring, primary keys
+// and subkeys, in order.
+// The usage flags of a primary key are read from the self-signature of its
+// primary identity, and those of a subkey from its binding signature.
+func (keyRing *KeyRing) SubkeyInfo() []SubkeyDetails {
+	var details []SubkeyDetails
+	for _, e := range keyRing.entities {
+		details = append(details, getSubkeyDetails(e.PrimaryKey, e.PrimaryIdentity().SelfSignature))
+		for _, subkey := range e.Subkeys {
+			details = append(details, getSubkeyDetails(subkey.PublicKey, subkey.Sig))
+		}
+	}
+
+	return details
+}
+
+// KeyInfo describes a primary key of a keyring.
+type KeyInfo struct {
+	KeyID       uint64
+	Fingerprint string
+	// CreationTime is the unix time at which the key was created.
+	CreationTime int64
+	Algorithm    string
+	// BitLength is the size of the key, like in SubkeyDetails.
+	BitLength int
+	IsPrivate bool
+}
+
+// KeyInfo returns the details of the primary key of every key in the keyring,
+// in order.
+func (keyRing *KeyRing) KeyInfo() []KeyInfo {
+	info := make([]KeyInfo, len(keyRing.entities))
+	for i, e := range keyRing.entities {
+		info[i] = KeyInfo{
+			KeyID:        e.PrimaryKey.KeyId,
+			Fingerprint:  (&Key{e}).GetFingerprint(),
+			CreationTime: e.PrimaryKey.CreationTime.Unix(),
+			Algorithm:    getAlgorithmName(e.PrimaryKey.PubKeyAlgo),
+			BitLength:    getBitLength(e.PrimaryKey),
+			IsPrivate:    e.PrivateKey != nil,
+		}
+	}
+
+	return info
+}
+
+// CheckKeyStrength returns an error naming the first key, or subkey, of the
+// keyring that is an RSA key of less than minRSABits bits, or that uses the
+// deprecated DSA or ElGamal algorithms.
+func (keyRing *KeyRing) CheckKeyStrength(minRSABits int) error {
+	for _, e := range keyRing.entities {
+		publicKeys := []*packet.PublicKey{e.PrimaryKey}
+		for _, subKey := range e.Subkeys {
+			publicKeys = append(publicKeys, subKey.PublicKey)
+		}
+
+		for _, publicKey := range publicKeys {
+			switch publicKey.PubKeyAlgo {
+			case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly:
+				if bitLength := getBitLength(publicKey); bitLength < minRSABits {
+					return errors.Errorf(
+						"gopenpgp: key %s is a %d bits RSA key, less than %d bits",
+						keyIDToHex(publicKey.KeyId), bitLength, minRSABits,
+					)
+				}
+			case packet.PubKeyAlgoDSA, packet.PubKeyAlgoElGamal:
+				return errors.Errorf(
+					"gopenpgp: key %s uses the deprecated %s algorithm",
+					keyIDToHex(publicKey.KeyId), getAlgorithmName(publicKey.PubKeyAlgo),
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getSubkeyDetails describes a key bound by the given self-signature.
+func getSubkeyDetails(publicKey *packet.PublicKey, sig *packet.Signature) SubkeyDetails {
+	details := SubkeyDetails{
+		KeyID:        publicKey.KeyId,
+		IsPrimary:    !publicKey.IsSubkey,
+		Algorithm:    getAlgorithmName(publicKey.PubKeyAlgo),
+		BitLength:    getBitLength(publicKey),
+		CreationTime: publicKey.CreationTime.Unix(),
+		CanEncrypt:   publicKey.PubKeyAlgo.CanEncrypt() && (!sig.FlagsValid || sig.FlagEncryptCommunications),
+		CanSign:      publicKey.PubKeyAlgo.CanSign() && (!sig.FlagsValid || sig.FlagSign),
+	}
+	if expiration, ok := keyExpiration(publicKey, sig); ok {
+		details.Expiration = expiration.Unix()
+	}
+
+	return details
+}
+
+// getBitLength returns the size of a key, or 0 if it is unknown.
+func getBitLength(publicKey *packet.PublicKey) int {
+	publicKey, err := reparsePublicKey(publicKey)
+	if err != nil {
+		return 0
+	}
+
+	switch pub := publicKey.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return pub.N.BitLen()
+	case *ecdsa.PublicKey:
+		return pub.Curve.Params().BitSize
+	case *ecdh.PublicKey:
+		if pub.Y == nil {
+			// Curve25519
+			return 255
+		}
+		return pub.Curve.Params().BitSize
+	case *ed25519.PublicKey:
+		return 255
+	}
+
+	bitLength, err := publicKey.BitLength()
+	if err != nil {
+		return 0
+	}
+	return int(bitLength)
+}
+
+// getAlgorithmName returns a readable name for a public key algorithm.
+func getAlgorithmName(algo packet.PublicKeyAlgorithm) string {
+	switch algo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly:
+		return "rsa"
+	case packet.PubKeyAlgoElGamal:
+		return "elgamal"
+	case packet.PubKeyAlgoDSA:
+		return "dsa"
+	case packet.PubKeyAlgoECDH:
+		return "ecdh"
+	case packet.PubKeyAlgoECDSA:
+		return "ecdsa"
+	case packet.PubKeyAlgoEdDSA:
+		return "eddsa"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,217 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// Packet tags of the secret key packets, see RFC 4880, section 4.3.
+const (
+	secretKeyPacketTag    = 5
+	secretSubkeyPacketTag = 7
+)
+
+// ExportPrivateWithS2K returns the armored private keys of the keyring,
+// locked with passphrase using the iterated and salted S2K with SHA256,
+// the given s2kCount and the given cipher algo, one of constants.AES128,
+// constants.AES192 or constants.AES256.
+// s2kCount must be between 65536 and 65011712, and is rounded to the
+// nearest value that can be encoded.
+// All the keys in the keyring must be unlocked. Only v4 keys are supported.
+func (keyRing *KeyRing) ExportPrivateWithS2K(passphrase []byte, s2kCount int, algo string) (string, error) {
+	cf, ok := symKeyAlgos[algo]
+	if !ok || (cf != packet.CipherAES128 && cf != packet.CipherAES192 && cf != packet.CipherAES256) {
+		return "", errors.New("gopenpgp: unsupported cipher for private key encryption: " + algo)
+	}
+	if s2kCount < 65536 || s2kCount > 65011712 {
+		return "", errors.New("gopenpgp: s2k count must be between 65536 and 65011712")
+	}
+	if !keyRing.IsFullyUnlocked() {
+		return "", ErrKeyringNotUnlocked
+	}
+
+	s2kConfig := &s2k.Config{
+		S2KMode:  3,
+		Hash:     crypto.SHA256,
+		S2KCount: s2kCount,
+	}
+
+	var buffer bytes.Buffer
+	for _, e := range keyRing.entities {
+		if err := writeLockedPrivateKey(&buffer, e.PrivateKey, passphrase, cf, s2kConfig); err != nil {
+			return "", err
+		}
+		for _, ident := range e.Identities {
+			if err := ident.UserId.Serialize(&buffer); err != nil {
+				return "", errors.Wrap(err, "gopenpgp: error in serializing user id")
+			}
+			if err := ident.SelfSignature.Serialize(&buffer); err != nil {
+				return "", errors.Wrap(err, "gopenpgp: error in serializing self-signature")
+			}
+		}
+		for _, subKey := range e.Subkeys {
+			if subKey.PrivateKey.Dummy() {
+				if err := subKey.PrivateKey.Serialize(&buffer); err != nil {
+					return "", errors.Wrap(err, "gopenpgp: error in serializing sub key")
+				}
+			} else if err := writeLockedPrivateKey(&buffer, subKey.PrivateKey, passphrase, cf, s2kConfig); err != nil {
+				return "", err
+			}
+			if err := subKey.Sig.Serialize(&buffer); err != nil {
+				return "", errors.Wrap(err, "gopenpgp: error in serializing sub key signature")
+			}
+		}
+	}
+
+	return armor.ArmorWithType(buffer.Bytes(), constants.PrivateKeyHeader)
+}
+
+// writeLockedPrivateKey writes to w a secret key packet containing the
+// unlocked privateKey, locked with passphrase using the given cipher and S2K.
+// See RFC 4880, section 5.5.3.
+func writeLockedPrivateKey(
+	w io.Writer,
+	privateKey *packet.PrivateKey,
+	passphrase []byte,
+	cf packet.CipherFunction,
+	s2kConfig *s2k.Config,
+) error {
+	if privateKey.Version != 4 {
+		return errors.New("gopenpgp: only v4 keys can be exported with a custom s2k")
+	}
+
+	var publicPacket, privatePacket bytes.Buffer
+	if err := privateKey.PublicKey.Serialize(&publicPacket); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in serializing public key")
+	}
+	if err := privateKey.Serialize(&privatePacket); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in serializing private key")
+	}
+	defer clearMem(privatePacket.Bytes())
+	publicBody, err := getPacketBody(publicPacket.Bytes())
+	if err != nil {
+		return err
+	}
+	privateBody, err := getPacketBody(privatePacket.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// The unlocked secret key packet is the public key, followed by the
+	// usage octet 0, the secret MPIs and a checksum.
+	secret := privateBody[len(publicBody)+1:]
+	secretLength, err := getSecretMPIsLength(privateKey.PubKeyAlgo, secret)
+	if err != nil {
+		return err
+	}
+	secret = secret[:secretLength]
+
+	var body bytes.Buffer
+	body.Write(publicBody)
+	// Usage octet 254: S2K specifier follows, SHA1 hash of the secret data.
+	body.Write([]byte{254, byte(cf)})
+
+	key := make([]byte, cf.KeySize())
+	defer clearMem(key)
+	if err = s2k.Serialize(&body, key, rand.Reader, passphrase, s2kConfig); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in deriving key")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: error in creating cipher")
+	}
+	iv := make([]byte, block.BlockSize())
+	if _, err = rand.Read(iv); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in generating iv")
+	}
+	body.Write(iv)
+
+	checksum := sha1.Sum(secret) //nolint:gosec
+	encrypted := append(append([]byte{}, secret...), checksum[:]...)
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(encrypted, encrypted)
+	body.Write(encrypted)
+
+	tag := byte(secretKeyPacketTag)
+	if privateKey.IsSubkey {
+		tag = secretSubkeyPacketTag
+	}
+	if _, err = w.Write(getPacketHeader(tag, body.Len())); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in writing private key")
+	}
+	if _, err = w.Write(body.Bytes()); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in writing private key")
+	}
+	return nil
+}
+
+// getSecretMPIsLength returns the length of the secret MPIs of a key
+// of the given algorithm at the start of data.
+func getSecretMPIsLength(algo packet.PublicKeyAlgorithm, data []byte) (int, error) {
+	count := 1
+	if algo == packet.PubKeyAlgoRSA || algo == packet.PubKeyAlgoRSAEncryptOnly || algo == packet.PubKeyAlgoRSASignOnly {
+		// d, p, q, u
+		count = 4
+	}
+
+	length := 0
+	for i := 0; i < count; i++ {
+		if len(data) < length+2 {
+			return 0, errors.New("gopenpgp: truncated private key data")
+		}
+		bitLength := int(data[length])<<8 | int(data[length+1])
+		length += 2 + (bitLength+7)/8
+	}
+	if len(data) < length {
+		return 0, errors.New("gopenpgp: truncated private key data")
+	}
+	return length, nil
+}
+
+// getPacketBody returns the body of a single new format packet with a
+// definite length, as serialized by the openpgp package.
+func getPacketBody(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0]&0xc0 != 0xc0 {
+		return nil, errors.New("gopenpgp: unexpected packet format")
+	}
+	var headerLength int
+	switch {
+	case data[1] < 192:
+		headerLength = 2
+	case data[1] < 224:
+		headerLength = 3
+	case data[1] == 255:
+		headerLength = 6
+	default:
+		return nil, errors.New("gopenpgp: unexpected partial packet length")
+	}
+	if len(data) < headerLength {
+		return nil, errors.New("gopenpgp: truncated packet")
+	}
+	return data[headerLength:], nil
+}
+
+// getPacketHeader returns a new format packet header, see RFC 4880, section 4.2.
+func getPacketHeader(tag byte, length int) []byte {
+	header := []byte{0xc0 | tag}
+	switch {
+	case length < 192:
+		return append(header, byte(length))
+	case length < 8384:
+		length -= 192
+		return append(header, 192+byte(length>>8), byte(length))
+	default:
+		return append(header, 255, byte(length>>24), byte(length>>16), byte(length>>8), byte(length))
+	}
+}
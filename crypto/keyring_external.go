@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/asn1"
+	"io"
+	"math/big"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// ExternalSignFunc signs a raw OpenPGP digest outside of the library,
+// e.g. in an HSM, and returns the signature value.
+// For RSA keys the signature is returned in r and s is ignored,
+// for ECDSA and EdDSA keys r and s are the two components of the signature.
+type ExternalSignFunc func(digest []byte) (r, s []byte, err error)
+
+// SignDetachedExternal generates and returns a PGPSignature for a given message Reader,
+// delegating the signing of the digest to signRaw.
+// The keyring only needs to contain the public key matching the external private key:
+// the library computes the digest, including the signature trailer, and assembles the
+// signature packet from the value returned by signRaw.
+func (keyRing *KeyRing) SignDetachedExternal(
+	message Reader,
+	hash crypto.Hash,
+	signRaw ExternalSignFunc,
+) (*PGPSignature, error) {
+	if !hash.Available() {
+		return nil, errors.New("gopenpgp: unavailable hash function")
+	}
+
+	var signingKey *packet.PublicKey
+	for _, e := range keyRing.entities {
+		if key, ok := e.SigningKey(getNow()); ok {
+			signingKey = key.PublicKey
+			break
+		}
+	}
+	if signingKey == nil {
+		return nil, errors.New("gopenpgp: cannot sign message, no signing key found")
+	}
+	switch signingKey.PubKeyAlgo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly, packet.PubKeyAlgoECDSA, packet.PubKeyAlgoEdDSA:
+	default:
+		return nil, errors.New("gopenpgp: unsupported public key algorithm for external signing")
+	}
+
+	signer := &externalSigner{
+		publicKey: signingKey,
+		signRaw:   signRaw,
+	}
+	privateKey := &packet.PrivateKey{
+		PublicKey:  *signingKey,
+		PrivateKey: signer,
+	}
+
+	config := &packet.Config{DefaultHash: hash, Time: getTimeGenerator()}
+	sigLifetimeSecs := config.SigLifetime()
+	sig := &packet.Signature{
+		SigType:         packet.SigTypeBinary,
+		PubKeyAlgo:      signingKey.PubKeyAlgo,
+		Hash:            hash,
+		CreationTime:    config.Now(),
+		SigLifetimeSecs: &sigLifetimeSecs,
+		IssuerKeyId:     &signingKey.KeyId,
+	}
+
+	h := hash.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading message")
+	}
+
+	err := sig.Sign(h, privateKey, config)
+	// The openpgp package ignores the errors of the signer for some
+	// algorithms, return the error of signRaw if any
+	if signer.err != nil {
+		return nil, signer.err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+
+	var outBuf bytes.Buffer
+	if err := sig.Serialize(&outBuf); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in serializing signature")
+	}
+
+	return NewPGPSignature(outBuf.Bytes()), nil
+}
+
+// externalSigner implements crypto.Signer on top of an ExternalSignFunc,
+// encoding the returned values in the format expected by the openpgp package.
+// The error of the last call to Sign is kept in err.
+type externalSigner struct {
+	publicKey *packet.PublicKey
+	signRaw   ExternalSignFunc
+	err       error
+}
+
+func (signer *externalSigner) Public() crypto.PublicKey {
+	return signer.publicKey.PublicKey
+}
+
+func (signer *externalSigner) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	sig, err := signer.sign(digest)
+	signer.err = err
+	return sig, err
+}
+
+// sign calls signRaw and encodes the signature value for the algorithm of the key.
+func (signer *externalSigner) sign(digest []byte) ([]byte, error) {
+	r, s, err := signer.signRaw(digest)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in external signing")
+	}
+
+	switch signer.publicKey.PubKeyAlgo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly:
+		return r, nil
+	case packet.PubKeyAlgoECDSA:
+		sig, err := asn1.Marshal(struct{ R, S *big.Int }{
+			new(big.Int).SetBytes(r),
+			new(big.Int).SetBytes(s),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in encoding ECDSA signature")
+		}
+		return sig, nil
+	case packet.PubKeyAlgoEdDSA:
+		if len(r) > 32 || len(s) > 32 {
+			return nil, errors.New("gopenpgp: invalid EdDSA signature length")
+		}
+		sig := make([]byte, 64)
+		copy(sig[32-len(r):32], r)
+		copy(sig[64-len(s):], s)
+		return sig, nil
+	default:
+		return nil, errors.New("gopenpgp: unsupported public key algorithm for external signing")
+	}
+}
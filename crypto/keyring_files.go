@@ -0,0 +1,109 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// filesLengthSize is the size of the big-endian length prefixes of the names
+// and contents of packed files.
+const filesLengthSize = 4
+
+// EncryptFiles encrypts several files into a single PGP message like Encrypt.
+// The files are packed, in the order of their names, as the length of the
+// name as a 4-byte big-endian integer, the name, the length of the content
+// and the content. The packed data is encrypted as binary data, and if
+// privateKey is provided the signature covers the packed data.
+// Messages encrypted with EncryptFiles must be decrypted with DecryptFiles.
+// * files      : The contents of the files, by name.
+// * privateKey : (optional) an unlocked private keyring to include signature in the message.
+func (keyRing *KeyRing) EncryptFiles(files map[string][]byte, privateKey *KeyRing) (*PGPMessage, error) {
+	packed, err := packFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyRing.Encrypt(NewPlainMessage(packed), privateKey)
+}
+
+// DecryptFiles decrypts a message encrypted with EncryptFiles like Decrypt,
+// and returns the contents of the files by name.
+// * message    : The encrypted input as a PGPMessage
+// * verifyKey  : Public key for signature verification (optional)
+// * verifyTime : Time at verification (necessary only if verifyKey is not nil)
+func (keyRing *KeyRing) DecryptFiles(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64,
+) (map[string][]byte, error) {
+	plainMessage, err := keyRing.Decrypt(message, verifyKey, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return unpackFiles(plainMessage.GetBinary())
+}
+
+// packFiles concatenates the length-prefixed names and contents of files.
+func packFiles(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	size := 0
+	for name, content := range files {
+		if uint64(len(name)) > 0xffffffff || uint64(len(content)) > 0xffffffff {
+			return nil, errors.New("gopenpgp: file is too long to be packed: " + name)
+		}
+		names = append(names, name)
+		size += 2*filesLengthSize + len(name) + len(content)
+	}
+	sort.Strings(names)
+
+	packed := make([]byte, 0, size)
+	for _, name := range names {
+		packed = appendLengthPrefixed(packed, []byte(name))
+		packed = appendLengthPrefixed(packed, files[name])
+	}
+	return packed, nil
+}
+
+// appendLengthPrefixed appends the length of data and data to packed.
+func appendLengthPrefixed(packed, data []byte) []byte {
+	var length [filesLengthSize]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	return append(append(packed, length[:]...), data...)
+}
+
+// unpackFiles returns the files packed by packFiles.
+func unpackFiles(packed []byte) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for len(packed) > 0 {
+		name, rest, err := readLengthPrefixed(packed)
+		if err != nil {
+			return nil, err
+		}
+		content, rest, err := readLengthPrefixed(rest)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := files[string(name)]; ok {
+			return nil, errors.New("gopenpgp: duplicate packed file: " + string(name))
+		}
+		files[string(name)] = content
+		packed = rest
+	}
+	return files, nil
+}
+
+// readLengthPrefixed returns the length-prefixed data at the start of packed,
+// and the rest of packed.
+func readLengthPrefixed(packed []byte) (data, rest []byte, err error) {
+	if len(packed) < filesLengthSize {
+		return nil, nil, errors.New("gopenpgp: packed files are truncated")
+	}
+
+	length := uint64(binary.BigEndian.Uint32(packed))
+	packed = packed[filesLengthSize:]
+	if length > uint64(len(packed)) {
+		return nil, nil, errors.New("gopenpgp: packed files are truncated")
+	}
+	return packed[:length], packed[length:], nil
+}
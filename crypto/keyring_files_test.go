@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing_EncryptFiles(t *testing.T) {
+	files := map[string][]byte{
+		"notes.txt":       []byte("some notes\n"),
+		"empty":           {},
+		"dir/binary.data": {0x00, 0xff, 0x10, 0x00},
+	}
+
+	ciphertext, err := keyRingTestPublic.EncryptFiles(files, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting files, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.DecryptFiles(ciphertext, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting files, got:", err)
+	}
+	assert.Exactly(t, files, decrypted)
+
+	ciphertext, err = keyRingTestPublic.EncryptFiles(map[string][]byte{}, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting no files, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.DecryptFiles(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting no files, got:", err)
+	}
+	assert.Len(t, decrypted, 0)
+
+	ciphertext, err = keyRingTestPublic.Encrypt(NewPlainMessageFromString("not packed"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, err = keyRingTestPrivate.DecryptFiles(ciphertext, nil, 0)
+	assert.NotNil(t, err)
+}
@@ -3,11 +3,17 @@ package crypto
 import (
 	"bytes"
 	"crypto"
+	"encoding/base64"
+	"encoding/binary"
 	"io"
 	"io/ioutil"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/ProtonMail/gopenpgp/v2/constants"
 	"github.com/pkg/errors"
@@ -47,6 +53,76 @@ func (keyRing *KeyRing) EncryptWithCompression(message *PlainMessage, privateKey
 	return NewPGPMessage(encrypted), nil
 }
 
+// EncryptWithCipher encrypts a PlainMessage like Encrypt, but with the given
+// cipher algo, one of constants.AES128, constants.AES256 or constants.CAST5,
+// instead of AES256. The cipher is only used if all the recipients list it in
+// their preferences, otherwise a cipher they all support is selected.
+// * message    : The plaintext input as a PlainMessage.
+// * privateKey : (optional) an unlocked private keyring to include signature in the message.
+// * algo       : The cipher algorithm.
+func (keyRing *KeyRing) EncryptWithCipher(message *PlainMessage, privateKey *KeyRing, algo string) (*PGPMessage, error) {
+	cf, ok := symKeyAlgos[algo]
+	if !ok || (cf != packet.CipherAES128 && cf != packet.CipherAES256 && cf != packet.CipherCAST5) {
+		return nil, errors.New("gopenpgp: unsupported cipher function: " + algo)
+	}
+
+	config := &packet.Config{DefaultCipher: cf, Time: getTimeGenerator()}
+	encrypted, err := asymmetricEncrypt(message, keyRing, privateKey, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPGPMessage(encrypted), nil
+}
+
+// EncryptWithSortedRecipients encrypts a PlainMessage, outputs a PGPMessage.
+// Unlike Encrypt, the session key packets are sorted by the key ID of the
+// recipients' encryption keys, so that the ciphertext neither depends on nor
+// reveals the order in which the recipients were added to the keyring.
+// If an unlocked private key is also provided it will also sign the message.
+// * message    : The plaintext input as a PlainMessage.
+// * privateKey : (optional) an unlocked private keyring to include signature in the message.
+func (keyRing *KeyRing) EncryptWithSortedRecipients(message *PlainMessage, privateKey *KeyRing) (*PGPMessage, error) {
+	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator()}
+	sortedKeyRing := &KeyRing{entities: keyRing.sortedByEncryptionKeyID()}
+	encrypted, err := asymmetricEncrypt(message, sortedKeyRing, privateKey, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPGPMessage(encrypted), nil
+}
+
+// CanDecrypt reports whether an armored or binary message is encrypted to
+// one of the unlocked private keys of the keyring, by matching the key IDs of
+// its public key encrypted session key packets. No decryption is performed.
+// If the message has hidden recipients, true is returned as long as the
+// keyring has a decryption key, since only trial decryption can tell whether
+// the message is encrypted to it.
+func (keyRing *KeyRing) CanDecrypt(message Reader) (bool, error) {
+	keyIDs, hasHiddenRecipient, err := getRecipientKeyIDs(message)
+	if err != nil {
+		return false, err
+	}
+
+	for _, keyID := range keyIDs {
+		for _, key := range keyRing.entities.KeysById(keyID) {
+			if key.PrivateKey != nil && !key.PrivateKey.Encrypted {
+				return true, nil
+			}
+		}
+	}
+
+	if hasHiddenRecipient {
+		for _, key := range keyRing.entities.DecryptionKeys() {
+			if key.PrivateKey != nil && !key.PrivateKey.Encrypted {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 // Decrypt decrypts encrypted string using pgp keys, returning a PlainMessage
 // * message    : The encrypted input as a PGPMessage
 // * verifyKey  : Public key for signature verification (optional)
@@ -60,6 +136,182 @@ func (keyRing *KeyRing) Decrypt(
 	return asymmetricDecrypt(message.NewReader(), keyRing, verifyKey, verifyTime)
 }
 
+// DecryptWithKeyID decrypts encrypted string using pgp keys, like Decrypt,
+// and also returns the key ID of the key, or subkey, of the keyring that
+// decrypted the message, e.g. to find the messages that can only be
+// decrypted by an old key.
+// * message    : The encrypted input as a PGPMessage
+// * verifyKey  : Public key for signature verification (optional)
+// * verifyTime : Time at verification (necessary only if verifyKey is not nil)
+func (keyRing *KeyRing) DecryptWithKeyID(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64,
+) (plainMessage *PlainMessage, decryptionKeyID uint64, err error) {
+	messageDetails, err := asymmetricDecryptStream(message.NewReader(), keyRing, verifyKey, verifyTime)
+	if err != nil {
+		return nil, 0, err
+	}
+	if messageDetails.DecryptedWith.PublicKey != nil {
+		decryptionKeyID = messageDetails.DecryptedWith.PublicKey.KeyId
+	}
+
+	plainMessage, err = readDecryptedMessage(messageDetails, verifyKey, verifyTime)
+	return plainMessage, decryptionKeyID, err
+}
+
+// DecryptBytesIfNeeded decrypts data if it is a binary PGP message, an armored
+// one, or a base64 encoded one without armor headers, and returns the
+// plaintext and true. Messages are detected by their first packet, which must
+// be a session key or an encrypted data packet. Any other data is returned
+// unchanged with false. The signature of the message, if any, is not verified.
+func (keyRing *KeyRing) DecryptBytesIfNeeded(data []byte) (plaintext []byte, decrypted bool, err error) {
+	binMessage := data
+	switch {
+	case isBinaryPGPMessage(binMessage):
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN "+constants.PGPMessageHeader+"-----")):
+		armored, err := NewPGPMessageFromArmored(string(data))
+		if err != nil || !isBinaryPGPMessage(armored.GetBinary()) {
+			return data, false, nil
+		}
+		binMessage = armored.GetBinary()
+	default:
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil || !isBinaryPGPMessage(decoded) {
+			return data, false, nil
+		}
+		binMessage = decoded
+	}
+
+	message, err := keyRing.Decrypt(NewPGPMessage(binMessage), nil, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	return message.GetBinary(), true, nil
+}
+
+// DecryptAllowExpired decrypts encrypted string using pgp keys, like Decrypt,
+// and also returns the embedded signature, if any.
+// If the embedded signature is valid but expired at verifyTime, or created
+// after it, no error is returned and expired is set to true, so that the
+// caller can still display who signed the message.
+// * message    : The encrypted input as a PGPMessage
+// * verifyKey  : Public key for signature verification (optional)
+// * verifyTime : Time at verification (necessary only if verifyKey is not nil)
+func (keyRing *KeyRing) DecryptAllowExpired(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64,
+) (plainMessage *PlainMessage, signature *PGPSignature, expired bool, err error) {
+	messageDetails, err := asymmetricDecryptStream(message.NewReader(), keyRing, verifyKey, verifyTime)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	body, err := ioutil.ReadAll(messageDetails.UnverifiedBody)
+	if err != nil {
+		return nil, nil, false, errors.Wrap(err, "gopenpgp: error in reading message body")
+	}
+
+	plainMessage = &PlainMessage{
+		Data:     body,
+		TextType: !messageDetails.LiteralData.IsBinary,
+		Filename: messageDetails.LiteralData.FileName,
+		Time:     messageDetails.LiteralData.Time,
+	}
+
+	if messageDetails.Signature != nil {
+		var outBuf bytes.Buffer
+		if err = messageDetails.Signature.Serialize(&outBuf); err != nil {
+			return nil, nil, false, errors.Wrap(err, "gopenpgp: error in serializing signature")
+		}
+		signature = NewPGPSignature(outBuf.Bytes())
+	}
+
+	if verifyKey != nil {
+		processSignatureExpiration(messageDetails, verifyTime)
+		if errors.Is(messageDetails.SignatureError, pgpErrors.ErrSignatureExpired) {
+			expired = true
+			messageDetails.SignatureError = nil
+		}
+		err = verifyDetailsSignature(messageDetails, verifyKey)
+	}
+
+	return plainMessage, signature, expired, err
+}
+
+// Errors returned by DecryptSecure when the message does not meet one of its requirements.
+var (
+	ErrMessageNotEncrypted        = errors.New("gopenpgp: message is not encrypted")
+	ErrMissingIntegrityProtection = errors.New("gopenpgp: message is not integrity protected")
+	ErrIntegrityCheckFailed       = errors.New("gopenpgp: message integrity check failed")
+)
+
+// DecryptSecure decrypts encrypted string using pgp keys, returning a
+// PlainMessage only if all the following requirements are met:
+// the message is encrypted, otherwise ErrMessageNotEncrypted is returned;
+// the message is integrity protected (MDC or AEAD), otherwise
+// ErrMissingIntegrityProtection is returned; the integrity check succeeds,
+// otherwise ErrIntegrityCheckFailed is returned; and the message carries a
+// valid signature from verifyKey at verifyTime, otherwise a
+// SignatureVerificationError is returned.
+// The whole message is processed before returning, so that no unverified
+// plaintext is ever released.
+// * message    : The encrypted input as a PGPMessage
+// * verifyKey  : Public key for signature verification (mandatory)
+// * verifyTime : Time at verification
+func (keyRing *KeyRing) DecryptSecure(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64,
+) (*PlainMessage, error) {
+	if verifyKey == nil {
+		return nil, errors.New("gopenpgp: a verification keyring is required")
+	}
+
+	messageDetails, err := asymmetricDecryptStream(message.NewReader(), keyRing, verifyKey, verifyTime)
+	if err != nil {
+		if isMissingIntegrityError(err) {
+			return nil, ErrMissingIntegrityProtection
+		}
+		return nil, err
+	}
+
+	if !messageDetails.IsEncrypted {
+		return nil, ErrMessageNotEncrypted
+	}
+
+	body, err := ioutil.ReadAll(messageDetails.UnverifiedBody)
+	if err != nil {
+		if isIntegrityCheckError(err) {
+			return nil, ErrIntegrityCheckFailed
+		}
+		return nil, errors.Wrap(err, "gopenpgp: error in reading message body")
+	}
+
+	processSignatureExpiration(messageDetails, verifyTime)
+	if err = verifyDetailsSignature(messageDetails, verifyKey); err != nil {
+		return nil, err
+	}
+
+	return &PlainMessage{
+		Data:     body,
+		TextType: !messageDetails.LiteralData.IsBinary,
+		Filename: messageDetails.LiteralData.FileName,
+		Time:     messageDetails.LiteralData.Time,
+	}, nil
+}
+
+// isMissingIntegrityError returns whether err was returned when decrypting
+// a message which is not integrity protected.
+func isMissingIntegrityError(err error) bool {
+	var unsupported pgpErrors.UnsupportedError
+	return errors.As(err, &unsupported) && strings.Contains(string(unsupported), "without MDC")
+}
+
+// isIntegrityCheckError returns whether err was returned when reading
+// a message whose integrity check failed.
+func isIntegrityCheckError(err error) bool {
+	var aeadError pgpErrors.AEADError
+	return errors.Is(err, pgpErrors.ErrMDCHashMismatch) ||
+		errors.Is(err, pgpErrors.ErrMDCMissing) ||
+		errors.As(err, &aeadError)
+}
+
 // SignDetached generates and returns a PGPSignature for a given PlainMessage.
 func (keyRing *KeyRing) SignDetached(message *PlainMessage) (*PGPSignature, error) {
 	signEntity, err := keyRing.getSigningEntity()
@@ -77,6 +329,14 @@ func (keyRing *KeyRing) SignDetached(message *PlainMessage) (*PGPSignature, erro
 	return NewPGPSignature(outBuf.Bytes()), nil
 }
 
+// SignDetachedWithKeyID generates and returns a PGPSignature for a given
+// PlainMessage, made with the unlocked key, or subkey, of the keyring with the
+// given key ID instead of the first signing key.
+// The key must be able to sign.
+func (keyRing *KeyRing) SignDetachedWithKeyID(message *PlainMessage, keyID uint64) (*PGPSignature, error) {
+	return keyRing.SignDetachedStreamWithKeyID(message.NewReader(), keyID)
+}
+
 // VerifyDetached verifies a PlainMessage with a detached PGPSignature
 // and returns a SignatureVerificationError if fails.
 func (keyRing *KeyRing) VerifyDetached(message *PlainMessage, signature *PGPSignature, verifyTime int64) error {
@@ -88,6 +348,256 @@ func (keyRing *KeyRing) VerifyDetached(message *PlainMessage, signature *PGPSign
 	)
 }
 
+// VerifyDetachedWithGrace verifies a PlainMessage with a detached PGPSignature
+// like VerifyDetached, but also accepts a signature that expired less than
+// grace before verifyTime. The key that made the signature must still be
+// valid, i.e. neither expired nor revoked, at verifyTime.
+func (keyRing *KeyRing) VerifyDetachedWithGrace(
+	message *PlainMessage, signature *PGPSignature, verifyTime int64, grace time.Duration,
+) error {
+	err := keyRing.VerifyDetached(message, signature, verifyTime)
+	if err == nil || verifyTime == 0 {
+		return err
+	}
+
+	p, parseErr := packet.Read(bytes.NewReader(signature.GetBinary()))
+	if parseErr != nil {
+		return err
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok || sig.SigLifetimeSecs == nil || *sig.SigLifetimeSecs == 0 || sig.IssuerKeyId == nil {
+		return err
+	}
+	expires := sig.CreationTime.Unix() + int64(*sig.SigLifetimeSecs)
+	if verifyTime <= expires || verifyTime-expires > int64(grace/time.Second) {
+		return err
+	}
+
+	if graceErr := keyRing.VerifyDetached(message, signature, expires); graceErr != nil {
+		return err
+	}
+	for _, key := range keyRing.entities.KeysById(*sig.IssuerKeyId) {
+		if revoked, expired := keyStatusAt(key, time.Unix(verifyTime, 0)); !revoked && !expired {
+			return nil
+		}
+	}
+	return err
+}
+
+// SignDetachedWithContext generates and returns a PGPSignature for a given
+// PlainMessage, bound to the given context, e.g. a request ID.
+// The signed data is the context prefix followed by the message, where the
+// prefix is the length of the context as a 4-byte big-endian integer followed
+// by the context itself. A signature made for one context is therefore not
+// valid for another one, or for the message alone.
+func (keyRing *KeyRing) SignDetachedWithContext(message *PlainMessage, context string) (*PGPSignature, error) {
+	return keyRing.SignDetachedStreamWithContext(message.NewReader(), context)
+}
+
+// VerifyDetachedWithContext verifies a PlainMessage with a detached
+// PGPSignature generated by SignDetachedWithContext for the same context,
+// and returns a SignatureVerificationError if fails.
+func (keyRing *KeyRing) VerifyDetachedWithContext(
+	message *PlainMessage,
+	signature *PGPSignature,
+	context string,
+	verifyTime int64,
+) error {
+	return keyRing.VerifyDetachedStreamWithContext(message.NewReader(), signature, context, verifyTime)
+}
+
+// VerifyItem is a message and its detached signature, to be verified by
+// VerifyDetachedBatch.
+type VerifyItem struct {
+	Message   *PlainMessage
+	Signature *PGPSignature
+}
+
+// VerifyDetachedBatch verifies many messages with their detached signatures
+// like VerifyDetached, in parallel on at most runtime.NumCPU() goroutines.
+// The returned errors are in the same order as items, and nil for the valid
+// signatures.
+func (keyRing *KeyRing) VerifyDetachedBatch(items []VerifyItem, verifyTime int64) []error {
+	results := make([]error, len(items))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	workers := runtime.NumCPU()
+	if workers > len(items) {
+		workers = len(items)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = keyRing.VerifyDetached(items[i].Message, items[i].Signature, verifyTime)
+			}
+		}()
+	}
+
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// ErrWeakKey is returned by VerifyDetachedMinKeySize when the signature was
+// made by a key that is too small.
+var ErrWeakKey = errors.New("gopenpgp: signing key is too weak")
+
+// VerifyDetachedMinKeySize verifies a PlainMessage with a detached PGPSignature
+// like VerifyDetached, and additionally returns ErrWeakKey if the signature was
+// made by an RSA key of less than minRSABits bits.
+// Keys of other algorithms are not checked.
+func (keyRing *KeyRing) VerifyDetachedMinKeySize(
+	message *PlainMessage, signature *PGPSignature, verifyTime int64, minRSABits int,
+) error {
+	if err := keyRing.VerifyDetached(message, signature, verifyTime); err != nil {
+		return err
+	}
+
+	signatureKeyIDs, ok := signature.GetSignatureKeyIDs()
+	if !ok {
+		return newSignatureNoVerifier()
+	}
+
+	for _, keyID := range signatureKeyIDs {
+		for _, key := range keyRing.entities.KeysById(keyID) {
+			switch key.PublicKey.PubKeyAlgo {
+			case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly:
+				bitLength, err := key.PublicKey.BitLength()
+				if err != nil {
+					return errors.Wrap(err, "gopenpgp: error in reading key size")
+				}
+				if int(bitLength) < minRSABits {
+					return ErrWeakKey
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// SigningSubkeyID returns the key ID of the subkey of the keyring that made
+// the signature, and false if the signature was made by a primary key or by
+// a key that is not in the keyring.
+// It does not verify the signature, use VerifyDetached to do so.
+func (keyRing *KeyRing) SigningSubkeyID(signature *PGPSignature) (uint64, bool) {
+	signatureKeyIDs, ok := signature.GetSignatureKeyIDs()
+	if !ok {
+		return 0, false
+	}
+
+	for _, keyID := range signatureKeyIDs {
+		for _, key := range keyRing.entities.KeysById(keyID) {
+			if key.PublicKey.KeyId != key.Entity.PrimaryKey.KeyId {
+				return keyID, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// ErrInvalidSubkeyBinding is returned by VerifyDetachedStrictBinding when the
+// signing subkey is not validly bound to its primary key.
+var ErrInvalidSubkeyBinding = errors.New("gopenpgp: signing subkey binding is invalid")
+
+// VerifyDetachedStrictBinding verifies a PlainMessage with a detached
+// PGPSignature like VerifyDetached, and additionally checks again that the
+// signing subkey, if any, is bound to its primary key: the binding signature
+// must be valid, allow signing, and contain a valid cross-signature of the
+// primary key by the subkey. ErrInvalidSubkeyBinding is returned otherwise.
+// Keys parsed by this library are already checked, this also covers keys
+// built or modified in memory, e.g. with NewKeyFromEntity.
+func (keyRing *KeyRing) VerifyDetachedStrictBinding(
+	message *PlainMessage, signature *PGPSignature, verifyTime int64,
+) error {
+	if err := keyRing.VerifyDetached(message, signature, verifyTime); err != nil {
+		return err
+	}
+
+	signatureKeyIDs, ok := signature.GetSignatureKeyIDs()
+	if !ok {
+		return newSignatureNoVerifier()
+	}
+
+	for _, keyID := range signatureKeyIDs {
+		for _, key := range keyRing.entities.KeysById(keyID) {
+			if key.PublicKey.KeyId == key.Entity.PrimaryKey.KeyId {
+				continue
+			}
+			binding := key.SelfSignature
+			if binding == nil || !binding.FlagsValid || !binding.FlagSign {
+				return ErrInvalidSubkeyBinding
+			}
+			if err := key.Entity.PrimaryKey.VerifyKeySignature(key.PublicKey, binding); err != nil {
+				return ErrInvalidSubkeyBinding
+			}
+		}
+	}
+
+	return nil
+}
+
+// Errors returned by SignaturesMatch when the signatures disagree.
+var (
+	ErrSignerMismatch  = errors.New("gopenpgp: signatures were made by different keys")
+	ErrContentMismatch = errors.New("gopenpgp: signatures cover different content")
+)
+
+// SignaturesMatch checks that a detached signature and an inline signed,
+// unencrypted message were made by the same key over the same content.
+// The inline signature is verified first with the keyring at verifyTime, and a
+// SignatureVerificationError is returned if it is invalid.
+// ErrSignerMismatch is returned if the detached signature was made by another
+// key, and ErrContentMismatch if it does not verify over the inline signed content.
+func (keyRing *KeyRing) SignaturesMatch(detached *PGPSignature, inlineSigned *PGPMessage, verifyTime int64) (bool, error) {
+	config := &packet.Config{
+		Time: func() time.Time {
+			if verifyTime == 0 {
+				// The time check is disabled, signature expiration errors
+				// are removed by processSignatureExpiration.
+				return getNow()
+			}
+			return time.Unix(verifyTime, 0)
+		},
+	}
+	md, err := openpgp.ReadMessage(inlineSigned.NewReader(), keyRing.entities, nil, config)
+	if err != nil {
+		return false, errors.Wrap(err, "gopenpgp: error in reading inline signed message")
+	}
+	if md.IsEncrypted {
+		return false, errors.New("gopenpgp: inline signed message must not be encrypted")
+	}
+
+	content, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return false, errors.Wrap(err, "gopenpgp: error in reading inline signed message")
+	}
+
+	processSignatureExpiration(md, verifyTime)
+	if err = verifyDetailsSignature(md, keyRing); err != nil {
+		return false, err
+	}
+
+	signatureKeyIDs, ok := detached.GetSignatureKeyIDs()
+	if !ok || len(signatureKeyIDs) != 1 || signatureKeyIDs[0] != md.SignedByKeyId {
+		return false, ErrSignerMismatch
+	}
+
+	if err = verifySignature(keyRing.entities, bytes.NewReader(content), detached.GetBinary(), verifyTime); err != nil {
+		return false, ErrContentMismatch
+	}
+
+	return true, nil
+}
+
 // SignDetachedEncrypted generates and returns a PGPMessage
 // containing an encrypted detached signature for a given PlainMessage.
 func (keyRing *KeyRing) SignDetachedEncrypted(message *PlainMessage, encryptionKeyRing *KeyRing) (encryptedSignature *PGPMessage, err error) {
@@ -118,8 +628,56 @@ func (keyRing *KeyRing) VerifyDetachedEncrypted(message *PlainMessage, encrypted
 	return keyRing.VerifyDetached(message, signature, verifyTime)
 }
 
+// EncryptAndDetachSign encrypts a PlainMessage to the keyring without
+// embedding a signature, and returns the ciphertext along with a detached
+// signature made by signKeyRing over the ciphertext (encrypt-then-sign).
+// The signature is a binary signature covering the exact bytes of the
+// unarmored PGPMessage, i.e. ciphertext.GetBinary(), and not the plaintext,
+// so it can be checked without decrypting the message.
+func (keyRing *KeyRing) EncryptAndDetachSign(
+	message *PlainMessage, signKeyRing *KeyRing,
+) (ciphertext *PGPMessage, signature *PGPSignature, err error) {
+	if signKeyRing == nil {
+		return nil, nil, errors.New("gopenpgp: no signing key ring provided")
+	}
+	ciphertext, err = keyRing.Encrypt(message, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err = signKeyRing.SignDetached(NewPlainMessage(ciphertext.GetBinary()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return ciphertext, signature, nil
+}
+
+// VerifyDetachedAndDecrypt verifies a detached signature over a ciphertext,
+// as produced by EncryptAndDetachSign, with verifyKey and decrypts the
+// ciphertext only if the signature is valid.
+// A SignatureVerificationError is returned if the verification fails.
+func (keyRing *KeyRing) VerifyDetachedAndDecrypt(
+	ciphertext *PGPMessage, signature *PGPSignature, verifyKey *KeyRing, verifyTime int64,
+) (*PlainMessage, error) {
+	if verifyKey == nil {
+		return nil, errors.New("gopenpgp: no verification key ring provided")
+	}
+	if err := verifyKey.VerifyDetached(NewPlainMessage(ciphertext.GetBinary()), signature, verifyTime); err != nil {
+		return nil, err
+	}
+	return keyRing.Decrypt(ciphertext, nil, 0)
+}
+
 // ------ INTERNAL FUNCTIONS -------
 
+// withSigningContext prefixes message with the length of context, as a 4-byte
+// big-endian integer, and context itself.
+func withSigningContext(context string, message Reader) Reader {
+	prefix := make([]byte, 4, 4+len(context))
+	binary.BigEndian.PutUint32(prefix, uint32(len(context)))
+	prefix = append(prefix, context...)
+	return io.MultiReader(bytes.NewReader(prefix), message)
+}
+
 // Core for encryption+signature (non-streaming) functions.
 func asymmetricEncrypt(
 	plainMessage *PlainMessage,
@@ -164,6 +722,10 @@ func asymmetricEncryptStream(
 ) (encryptWriter io.WriteCloser, err error) {
 	var signEntity *openpgp.Entity
 
+	if err := publicKey.checkNotRevoked(); err != nil {
+		return nil, err
+	}
+
 	if privateKey != nil && len(privateKey.entities) > 0 {
 		var err error
 		signEntity, err = privateKey.getSigningEntity()
@@ -197,6 +759,14 @@ func asymmetricDecrypt(
 		return nil, err
 	}
 
+	return readDecryptedMessage(messageDetails, verifyKey, verifyTime)
+}
+
+// readDecryptedMessage reads the body of a decrypted message and verifies its
+// embedded signature if verifyKey is not nil.
+func readDecryptedMessage(
+	messageDetails *openpgp.MessageDetails, verifyKey *KeyRing, verifyTime int64,
+) (*PlainMessage, error) {
 	body, err := ioutil.ReadAll(messageDetails.UnverifiedBody)
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in reading message body")
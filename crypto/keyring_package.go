@@ -0,0 +1,106 @@
+package crypto
+
+import (
+	"io/ioutil"
+	"strings"
+
+	pgpArmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// PackageRecipientHeader is the armor header listing the fingerprints of the
+// recipients of a package.
+const PackageRecipientHeader = "Recipient"
+
+// ErrNotPackageRecipient is returned by OpenPackage when the package is not
+// addressed to the keyring.
+var ErrNotPackageRecipient = errors.New("gopenpgp: package is not addressed to this keyring")
+
+// EncryptPackage encrypts and signs a PlainMessage like Encrypt, and returns a
+// package: an armored PGP message with an additional Recipient armor header
+// listing the comma separated hex fingerprints of the primary keys of the
+// recipients. The header lets a recipient know that the package is addressed to
+// them without decrypting it, and only reveals who the recipients are.
+// Since packages are regular armored messages, any OpenPGP implementation can
+// decrypt them.
+// * message    : The plaintext input as a PlainMessage.
+// * privateKey : (optional) an unlocked private keyring to include signature in the message.
+func (keyRing *KeyRing) EncryptPackage(message *PlainMessage, privateKey *KeyRing) (string, error) {
+	ciphertext, err := keyRing.Encrypt(message, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	fingerprints := make([]string, len(keyRing.entities))
+	for i, key := range keyRing.GetKeys() {
+		fingerprints[i] = key.GetFingerprint()
+	}
+
+	return armor.ArmorWithTypeAndHeaders(
+		ciphertext.GetBinary(),
+		constants.PGPMessageHeader,
+		map[string]string{PackageRecipientHeader: strings.Join(fingerprints, ",")},
+	)
+}
+
+// GetPackageRecipients returns the hex fingerprints listed in the Recipient
+// header of a package, without decrypting it.
+func GetPackageRecipients(pkg string) ([]string, error) {
+	recipients, _, err := readPackage(pkg)
+	return recipients, err
+}
+
+// OpenPackage checks that a package created with EncryptPackage is addressed
+// to the keyring, and decrypts it like Decrypt.
+// ErrNotPackageRecipient is returned if none of the recipients of the
+// package is in the keyring.
+// * pkg        : The armored package.
+// * verifyKey  : Public key for signature verification (optional).
+// * verifyTime : Time at verification (necessary only if verifyKey is not nil).
+func (keyRing *KeyRing) OpenPackage(pkg string, verifyKey *KeyRing, verifyTime int64) (*PlainMessage, error) {
+	recipients, data, err := readPackage(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	isRecipient := false
+	for _, key := range keyRing.GetKeys() {
+		for _, recipient := range recipients {
+			if strings.EqualFold(recipient, key.GetFingerprint()) {
+				isRecipient = true
+			}
+		}
+	}
+	if !isRecipient {
+		return nil, ErrNotPackageRecipient
+	}
+
+	return keyRing.Decrypt(NewPGPMessage(data), verifyKey, verifyTime)
+}
+
+// readPackage returns the recipients and the binary message of a package.
+func readPackage(pkg string) (recipients []string, data []byte, err error) {
+	block, err := pgpArmor.Decode(strings.NewReader(pkg))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "gopenpgp: unable to unarmor package")
+	}
+	if block.Type != constants.PGPMessageHeader {
+		return nil, nil, errors.New("gopenpgp: package is not a PGP message")
+	}
+
+	header, ok := block.Header[PackageRecipientHeader]
+	if !ok || header == "" {
+		return nil, nil, errors.New("gopenpgp: package has no recipient header")
+	}
+	for _, recipient := range strings.Split(header, ",") {
+		recipients = append(recipients, strings.TrimSpace(recipient))
+	}
+
+	data, err = ioutil.ReadAll(block.Body)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "gopenpgp: error in reading package")
+	}
+	return recipients, data, nil
+}
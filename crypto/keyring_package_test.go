@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing_EncryptPackage(t *testing.T) {
+	message := NewPlainMessageFromString("package content")
+
+	pkg, err := keyRingTestPublic.EncryptPackage(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting package, got:", err)
+	}
+	assert.Contains(t, pkg, "Recipient: "+keyRingTestPublic.GetKeys()[0].GetFingerprint())
+
+	recipients, err := GetPackageRecipients(pkg)
+	if err != nil {
+		t.Fatal("Expected no error while reading package recipients, got:", err)
+	}
+	assert.Exactly(t, []string{keyRingTestPublic.GetKeys()[0].GetFingerprint()}, recipients)
+
+	decrypted, err := keyRingTestPrivate.OpenPackage(pkg, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while opening package, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	// The package is a regular armored message
+	ciphertext, err := NewPGPMessageFromArmored(pkg)
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring package, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting package, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	_, err = ecKeyRing.OpenPackage(pkg, nil, 0)
+	assert.Exactly(t, ErrNotPackageRecipient, err)
+
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring message, got:", err)
+	}
+	_, err = GetPackageRecipients(armored)
+	assert.NotNil(t, err)
+
+	_, err = keyRingTestPrivate.OpenPackage(strings.Replace(pkg, "MESSAGE", "SIGNATURE", 2), nil, 0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// paddingLengthSize is the size of the big-endian length prefix of padded data.
+const paddingLengthSize = 4
+
+// EncryptWithBlockPadding encrypts a PlainMessage like Encrypt, after padding
+// its data so that the length of the literal data is the next multiple of
+// blockSize, which hides the exact length of the plaintext within a block.
+// This is block padding, not padding to a fixed size: messages longer than a
+// block still have longer ciphertexts, and the size of the ciphertext also
+// depends on the key and signature packets.
+// The padded data is the length of the data as a 4-byte big-endian integer,
+// followed by the data and by zeros. It is encrypted as binary data without
+// the filename of the message, whose length would leak into the ciphertext,
+// and if privateKey is provided the signature covers the padded data.
+// Messages encrypted with EncryptWithBlockPadding must be decrypted with
+// DecryptWithBlockPadding.
+// * message    : The plaintext input as a PlainMessage.
+// * privateKey : (optional) an unlocked private keyring to include signature in the message.
+// * blockSize  : The positive block size, in bytes.
+func (keyRing *KeyRing) EncryptWithBlockPadding(
+	message *PlainMessage, privateKey *KeyRing, blockSize int,
+) (*PGPMessage, error) {
+	padded, err := padData(message.GetBinary(), blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyRing.Encrypt(&PlainMessage{
+		Data:     padded,
+		TextType: false,
+		Time:     message.Time,
+	}, privateKey)
+}
+
+// DecryptWithBlockPadding decrypts a message encrypted with
+// EncryptWithBlockPadding like Decrypt, and removes the padding from the
+// returned PlainMessage. The returned message is binary, without filename.
+// * message    : The encrypted input as a PGPMessage
+// * verifyKey  : Public key for signature verification (optional)
+// * verifyTime : Time at verification (necessary only if verifyKey is not nil)
+func (keyRing *KeyRing) DecryptWithBlockPadding(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64,
+) (*PlainMessage, error) {
+	plainMessage, err := keyRing.Decrypt(message, verifyKey, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := unpadData(plainMessage.Data)
+	if err != nil {
+		return nil, err
+	}
+	plainMessage.Data = data
+	return plainMessage, nil
+}
+
+// padData prefixes data with its length and pads it with zeros to a multiple
+// of blockSize.
+func padData(data []byte, blockSize int) ([]byte, error) {
+	if blockSize <= 0 {
+		return nil, errors.New("gopenpgp: padding block size must be positive")
+	}
+	if uint64(len(data)) > 0xffffffff {
+		return nil, errors.New("gopenpgp: data is too long to be padded")
+	}
+
+	length := paddingLengthSize + len(data)
+	if remainder := length % blockSize; remainder != 0 {
+		length += blockSize - remainder
+	}
+
+	padded := make([]byte, length)
+	binary.BigEndian.PutUint32(padded, uint32(len(data)))
+	copy(padded[paddingLengthSize:], data)
+	return padded, nil
+}
+
+// unpadData returns the data padded by padData.
+func unpadData(padded []byte) ([]byte, error) {
+	if len(padded) < paddingLengthSize {
+		return nil, errors.New("gopenpgp: padded data is too short")
+	}
+
+	length := uint64(binary.BigEndian.Uint32(padded))
+	if length > uint64(len(padded)-paddingLengthSize) {
+		return nil, errors.New("gopenpgp: invalid padding length")
+	}
+	return padded[paddingLengthSize : paddingLengthSize+length], nil
+}
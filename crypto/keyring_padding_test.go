@@ -0,0 +1,69 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing_EncryptWithBlockPadding(t *testing.T) {
+	paddedSizes := map[string]int{
+		"":                                     32,
+		"1234":                                 32,
+		"a secret field":                       32,
+		"a longer secret field, in two blocks": 64,
+	}
+	for text, paddedSize := range paddedSizes {
+		message := NewPlainMessageFromString(text)
+
+		ciphertext, err := keyRingTestPublic.EncryptWithBlockPadding(message, keyRingTestPrivate, 32)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting with padding, got:", err)
+		}
+
+		decrypted, err := keyRingTestPrivate.DecryptWithBlockPadding(ciphertext, keyRingTestPublic, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while decrypting with padding, got:", err)
+		}
+		assert.Exactly(t, text, decrypted.GetString())
+
+		padded, err := keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Len(t, padded.Data, paddedSize)
+	}
+
+	var dataPacketSizes []int
+	for _, filename := range []string{"", "a-long-file-name.txt"} {
+		message := NewPlainMessageFromString("data")
+		message.Filename = filename
+
+		ciphertext, err := keyRingTestPublic.EncryptWithBlockPadding(message, nil, 32)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting with padding, got:", err)
+		}
+		split, err := ciphertext.SeparateKeyAndData(1024, 0)
+		if err != nil {
+			t.Fatal("Expected no error while splitting, got:", err)
+		}
+		dataPacketSizes = append(dataPacketSizes, len(split.GetBinaryDataPacket()))
+
+		decrypted, err := keyRingTestPrivate.DecryptWithBlockPadding(ciphertext, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting with padding, got:", err)
+		}
+		assert.Exactly(t, "", decrypted.GetFilename())
+	}
+	assert.Exactly(t, dataPacketSizes[0], dataPacketSizes[1])
+
+	_, err := keyRingTestPublic.EncryptWithBlockPadding(NewPlainMessageFromString("data"), nil, 0)
+	assert.NotNil(t, err)
+
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("abc"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, err = keyRingTestPrivate.DecryptWithBlockPadding(ciphertext, nil, 0)
+	assert.NotNil(t, err)
+}
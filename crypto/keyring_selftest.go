@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// Errors returned by KeyRing.SelfTest, one for each step of the test.
+var (
+	ErrSelfTestUnlock  = errors.New("gopenpgp: self-test failed to unlock the keyring")
+	ErrSelfTestEncrypt = errors.New("gopenpgp: self-test failed to encrypt")
+	ErrSelfTestDecrypt = errors.New("gopenpgp: self-test failed to decrypt")
+	ErrSelfTestSign    = errors.New("gopenpgp: self-test failed to sign")
+	ErrSelfTestVerify  = errors.New("gopenpgp: self-test failed to verify")
+)
+
+// selfTestPlaintext is the message encrypted and signed by KeyRing.SelfTest.
+const selfTestPlaintext = "gopenpgp keyring self-test"
+
+// SelfTest checks that the keyring can encrypt and decrypt a message, and sign
+// and verify a detached signature. Keys that are still locked are unlocked with
+// passphrase, which is ignored if all the keys are already unlocked.
+// The test operates on a copy of the keyring, which is cleared afterwards,
+// so no unlocked key material is left behind.
+// The returned error wraps the ErrSelfTest* error of the first failing step,
+// which can be checked with errors.Is.
+func (keyRing *KeyRing) SelfTest(passphrase []byte) error {
+	testKeyRing := &KeyRing{FirstKeyID: keyRing.FirstKeyID}
+	defer testKeyRing.ClearPrivateParams()
+
+	for _, key := range keyRing.GetKeys() {
+		var testKey *Key
+		locked, err := key.IsLocked()
+		if err == nil && locked {
+			testKey, err = key.Unlock(passphrase)
+		} else {
+			testKey, err = key.Copy()
+		}
+		if err != nil {
+			return &selfTestError{ErrSelfTestUnlock, err}
+		}
+		testKeyRing.appendKey(testKey)
+	}
+
+	message := NewPlainMessageFromString(selfTestPlaintext)
+
+	ciphertext, err := testKeyRing.Encrypt(message, nil)
+	if err != nil {
+		return &selfTestError{ErrSelfTestEncrypt, err}
+	}
+
+	decrypted, err := testKeyRing.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		return &selfTestError{ErrSelfTestDecrypt, err}
+	}
+	if !bytes.Equal(decrypted.GetBinary(), message.GetBinary()) {
+		return &selfTestError{ErrSelfTestDecrypt, errors.New("gopenpgp: decrypted message does not match")}
+	}
+
+	signature, err := testKeyRing.SignDetached(message)
+	if err != nil {
+		return &selfTestError{ErrSelfTestSign, err}
+	}
+
+	if err := testKeyRing.VerifyDetached(message, signature, GetUnixTime()); err != nil {
+		return &selfTestError{ErrSelfTestVerify, err}
+	}
+
+	return nil
+}
+
+// selfTestError wraps the error of a failed self-test step, and matches the
+// ErrSelfTest* error of that step.
+type selfTestError struct {
+	step  error
+	cause error
+}
+
+func (err *selfTestError) Error() string {
+	return err.step.Error() + ": " + err.cause.Error()
+}
+
+func (err *selfTestError) Is(target error) bool {
+	return target == err.step
+}
+
+func (err *selfTestError) Unwrap() error {
+	return err.cause
+}
@@ -0,0 +1,40 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRingSelfTest(t *testing.T) {
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring key, got:", err)
+	}
+
+	// NewKeyRing refuses locked keys
+	lockedKeyRing := &KeyRing{entities: openpgp.EntityList{lockedKey.entity}}
+
+	if err = lockedKeyRing.SelfTest(testMailboxPassword); err != nil {
+		t.Fatal("Expected no error when self-testing locked keyring, got:", err)
+	}
+
+	locked, err := lockedKey.IsLocked()
+	if err != nil {
+		t.Fatal("Expected no error when checking key, got:", err)
+	}
+	assert.True(t, locked)
+
+	if err = keyRingTestPrivate.SelfTest(nil); err != nil {
+		t.Fatal("Expected no error when self-testing unlocked keyring, got:", err)
+	}
+
+	err = lockedKeyRing.SelfTest([]byte("wrong passphrase"))
+	assert.True(t, errors.Is(err, ErrSelfTestUnlock))
+
+	err = keyRingTestPublic.SelfTest(nil)
+	assert.True(t, errors.Is(err, ErrSelfTestDecrypt))
+	assert.False(t, errors.Is(err, ErrSelfTestEncrypt))
+}
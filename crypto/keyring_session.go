@@ -78,6 +78,13 @@ func (keyRing *KeyRing) EncryptSessionKey(sk *SessionKey) ([]byte, error) {
 		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt session key")
 	}
 
+	if len(keyRing.entities) == 0 {
+		return nil, ErrNoKeyInKeyring
+	}
+	if err := keyRing.checkNotRevoked(); err != nil {
+		return nil, err
+	}
+
 	pubKeys := make([]*packet.PublicKey, 0, len(keyRing.entities))
 	for _, e := range keyRing.entities {
 		encryptionKey, ok := e.EncryptionKey(getNow())
@@ -86,9 +93,6 @@ func (keyRing *KeyRing) EncryptSessionKey(sk *SessionKey) ([]byte, error) {
 		}
 		pubKeys = append(pubKeys, encryptionKey.PublicKey)
 	}
-	if len(pubKeys) == 0 {
-		return nil, errors.New("cannot set key: no public key available")
-	}
 
 	for _, pub := range pubKeys {
 		if err := packet.SerializeEncryptedKey(outbuf, pub, cf, sk.Key, nil); err != nil {
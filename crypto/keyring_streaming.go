@@ -3,11 +3,16 @@ package crypto
 import (
 	"bytes"
 	"crypto"
+	"encoding/hex"
 	"io"
+	"mime"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
 	"github.com/pkg/errors"
 )
 
@@ -43,7 +48,85 @@ func (keyRing *KeyRing) EncryptStream(
 	signKeyRing *KeyRing,
 ) (plainMessageWriter WriteCloser, err error) {
 	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator()}
+	return keyRing.encryptStream(pgpMessageWriter, plainMessageMetadata, signKeyRing, config)
+}
+
+// EncryptStreamWithCompression is used to encrypt data as a Writer,
+// compressing the data before encryption.
+// It takes a writer for the encrypted data and returns a WriteCloser for the plaintext data
+// If signKeyRing is not nil, it is used to do an embedded signature.
+func (keyRing *KeyRing) EncryptStreamWithCompression(
+	pgpMessageWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+	signKeyRing *KeyRing,
+) (plainMessageWriter WriteCloser, err error) {
+	config := &packet.Config{
+		DefaultCipher:          packet.CipherAES256,
+		Time:                   getTimeGenerator(),
+		DefaultCompressionAlgo: constants.DefaultCompression,
+		CompressionConfig:      &packet.CompressionConfig{Level: constants.DefaultCompressionLevel},
+	}
+	return keyRing.encryptStream(pgpMessageWriter, plainMessageMetadata, signKeyRing, config)
+}
 
+// EncryptStreamAuto is used to encrypt data of the given MIME content type as
+// a Writer. The data is compressed only if IsCompressibleContentType returns
+// true for contentType. To override this choice, use EncryptStream or
+// EncryptStreamWithCompression instead.
+// It takes a writer for the encrypted data and returns a WriteCloser for the plaintext data
+// If signKeyRing is not nil, it is used to do an embedded signature.
+func (keyRing *KeyRing) EncryptStreamAuto(
+	pgpMessageWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+	signKeyRing *KeyRing,
+	contentType string,
+) (plainMessageWriter WriteCloser, err error) {
+	if IsCompressibleContentType(contentType) {
+		return keyRing.EncryptStreamWithCompression(pgpMessageWriter, plainMessageMetadata, signKeyRing)
+	}
+	return keyRing.EncryptStream(pgpMessageWriter, plainMessageMetadata, signKeyRing)
+}
+
+// CompressibleContentTypes overrides IsCompressibleContentType for the listed
+// MIME media types, without parameters: true to compress data of the type,
+// false not to. Entries can be added or changed to tune the choice of
+// EncryptStreamAuto, before any encryption is performed, as the map is not
+// safe for concurrent modification.
+var CompressibleContentTypes = map[string]bool{
+	"application/json":       true,
+	"application/xml":        true,
+	"application/javascript": true,
+	"application/yaml":       true,
+	"application/x-yaml":     true,
+}
+
+// IsCompressibleContentType returns whether data of the given MIME content
+// type is worth compressing before encryption, i.e. whether it is text:
+// text/*, JSON, XML, JavaScript and YAML. Other content types, in particular
+// images, audio, video and archives which are already compressed, are not.
+// The media types listed in CompressibleContentTypes take precedence.
+func IsCompressibleContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if compressible, ok := CompressibleContentTypes[mediaType]; ok {
+		return compressible
+	}
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml")
+}
+
+// encryptStream is the core of the streaming encryption functions.
+func (keyRing *KeyRing) encryptStream(
+	pgpMessageWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+	signKeyRing *KeyRing,
+	config *packet.Config,
+) (plainMessageWriter WriteCloser, err error) {
 	if plainMessageMetadata == nil {
 		// Use sensible default metadata
 		plainMessageMetadata = &PlainMessageMetadata{
@@ -153,6 +236,29 @@ func (msg *PlainMessageReader) GetMetadata() *PlainMessageMetadata {
 	}
 }
 
+// GetDecryptionKeyID returns the ID of the key that decrypted the message.
+func (msg *PlainMessageReader) GetDecryptionKeyID() uint64 {
+	if msg.details.DecryptedWith.PublicKey == nil {
+		return 0
+	}
+	return msg.details.DecryptedWith.PublicKey.KeyId
+}
+
+// GetSigningKeyFingerprint returns the hex fingerprint of the key, or subkey,
+// of the verification keyring that signed the message.
+// This method needs to be called once all the data has been read, so that
+// the plaintext does not need to be buffered. It does not verify the
+// signature, use VerifySignature to do so.
+func (msg *PlainMessageReader) GetSigningKeyFingerprint() (string, error) {
+	if !msg.readAll {
+		return "", errors.New("gopenpgp: can't get the signing key until the message reader has been read entirely")
+	}
+	if !msg.details.IsSigned || msg.details.SignedBy == nil {
+		return "", errors.New("gopenpgp: the message is not signed by a key of the verification keyring")
+	}
+	return hex.EncodeToString(msg.details.SignedBy.PublicKey.Fingerprint), nil
+}
+
 // Read is used to access the message decrypted data.
 // Makes PlainMessageReader implement the Reader interface.
 func (msg *PlainMessageReader) Read(b []byte) (n int, err error) {
@@ -208,6 +314,96 @@ func (keyRing *KeyRing) DecryptStream(
 	}, err
 }
 
+// ErrMessageNotFullyRead is returned by SecurePlainMessageReader.Close when
+// the message has not been read entirely.
+var ErrMessageNotFullyRead = errors.New("gopenpgp: message has not been read entirely")
+
+// SecurePlainMessageReader streams the plaintext of a message decrypted with
+// DecryptSecureStream. Close must be called once the plaintext has been read,
+// and the plaintext must be discarded unless it returns nil.
+type SecurePlainMessageReader struct {
+	details       *openpgp.MessageDetails
+	verifyKeyRing *KeyRing
+	verifyTime    int64
+	readAll       bool
+	readErr       error
+}
+
+// Read is used to access the decrypted data, which is not verified until
+// Close is called. ErrIntegrityCheckFailed is returned if the integrity
+// check fails once the end of the message is reached.
+// Makes SecurePlainMessageReader implement the Reader interface.
+func (msg *SecurePlainMessageReader) Read(b []byte) (n int, err error) {
+	if msg.readErr != nil {
+		return 0, msg.readErr
+	}
+
+	n, err = msg.details.UnverifiedBody.Read(b)
+	switch {
+	case errors.Is(err, io.EOF):
+		msg.readAll = true
+	case err != nil && isIntegrityCheckError(err):
+		msg.readErr = ErrIntegrityCheckFailed
+		err = msg.readErr
+	case err != nil:
+		msg.readErr = errors.Wrap(err, "gopenpgp: error in reading message body")
+		err = msg.readErr
+	}
+	return n, err
+}
+
+// Close checks the requirements of DecryptSecure once the message has been
+// read: it returns ErrMessageNotFullyRead if the end of the message has not
+// been reached, ErrIntegrityCheckFailed if the integrity check failed, and a
+// SignatureVerificationError if the message does not carry a valid signature
+// from the verification keyring. It returns nil only if all the requirements
+// are met. Makes SecurePlainMessageReader implement the io.Closer interface.
+func (msg *SecurePlainMessageReader) Close() error {
+	if msg.readErr != nil {
+		return msg.readErr
+	}
+	if !msg.readAll {
+		return ErrMessageNotFullyRead
+	}
+
+	processSignatureExpiration(msg.details, msg.verifyTime)
+	return verifyDetailsSignature(msg.details, msg.verifyKeyRing)
+}
+
+// DecryptSecureStream is the streaming counterpart of DecryptSecure, for
+// messages too large to be buffered. It returns a SecurePlainMessageReader
+// streaming the plaintext, whose Close method reports whether the message
+// was integrity protected, passed its integrity check and carried a valid
+// signature from verifyKeyRing at verifyTime.
+// ErrMessageNotEncrypted and ErrMissingIntegrityProtection are returned
+// immediately, as they are known before any plaintext is read.
+func (keyRing *KeyRing) DecryptSecureStream(
+	message Reader,
+	verifyKeyRing *KeyRing,
+	verifyTime int64,
+) (*SecurePlainMessageReader, error) {
+	if verifyKeyRing == nil {
+		return nil, errors.New("gopenpgp: a verification keyring is required")
+	}
+
+	messageDetails, err := asymmetricDecryptStream(message, keyRing, verifyKeyRing, verifyTime)
+	if err != nil {
+		if isMissingIntegrityError(err) {
+			return nil, ErrMissingIntegrityProtection
+		}
+		return nil, err
+	}
+	if !messageDetails.IsEncrypted {
+		return nil, ErrMessageNotEncrypted
+	}
+
+	return &SecurePlainMessageReader{
+		details:       messageDetails,
+		verifyKeyRing: verifyKeyRing,
+		verifyTime:    verifyTime,
+	}, nil
+}
+
 // DecryptSplitStream is used to decrypt a split pgp message as a Reader.
 // It takes a key packet and a reader for the data packet
 // and returns a PlainMessageReader for the plaintext data.
@@ -229,6 +425,79 @@ func (keyRing *KeyRing) DecryptSplitStream(
 	)
 }
 
+// DecryptHidden is used to decrypt a pgp message as a Reader, like DecryptStream,
+// when the message is encrypted to anonymous recipients (key ID 0).
+// Each anonymous session key packet is trial decrypted once against the
+// decryption keys of the keyring, and the recovered session key is used
+// to decrypt the message. PlainMessageReader.GetDecryptionKeyID() reports
+// which key succeeded.
+func (keyRing *KeyRing) DecryptHidden(
+	message Reader,
+	verifyKeyRing *KeyRing,
+	verifyTime int64,
+) (plainMessage *PlainMessageReader, err error) {
+	keyPackets := &packetRecorder{}
+	packets := packet.NewReader(io.TeeReader(message, keyPackets))
+	sessionKey, decryptionKey, dataPacket, err := keyRing.findHiddenRecipient(packets)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessionKey == nil {
+		// No anonymous recipient, decrypt the message as usual
+		return keyRing.DecryptStream(
+			io.MultiReader(&keyPackets.buf, message),
+			verifyKeyRing,
+			verifyTime,
+		)
+	}
+
+	keyPackets.stop()
+	messageDetails, err := decryptDataPacketWithSessionKey(sessionKey, dataPacket, verifyKeyRing)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading message")
+	}
+	messageDetails.IsEncrypted = true
+	messageDetails.DecryptedWith = decryptionKey
+
+	return &PlainMessageReader{
+		messageDetails,
+		verifyKeyRing,
+		verifyTime,
+		false,
+	}, nil
+}
+
+// packetRecorder keeps a copy of the data written to it until stop is called.
+type packetRecorder struct {
+	buf     bytes.Buffer
+	stopped bool
+}
+
+func (recorder *packetRecorder) Write(b []byte) (int, error) {
+	if recorder.stopped {
+		return len(b), nil
+	}
+	return recorder.buf.Write(b)
+}
+
+func (recorder *packetRecorder) stop() {
+	recorder.stopped = true
+	recorder.buf.Reset()
+}
+
+// PeekFileName decrypts just enough of a pgp message to read the header of
+// its literal data packet, and returns the filename and binary flag hints
+// without reading the body of the message.
+func (keyRing *KeyRing) PeekFileName(message Reader) (filename string, isBinary bool, err error) {
+	messageDetails, err := asymmetricDecryptStream(message, keyRing, nil, 0)
+	if err != nil {
+		return "", false, err
+	}
+
+	return messageDetails.LiteralData.FileName, messageDetails.LiteralData.IsBinary, nil
+}
+
 // SignDetachedStream generates and returns a PGPSignature for a given message Reader.
 func (keyRing *KeyRing) SignDetachedStream(message Reader) (*PGPSignature, error) {
 	signEntity, err := keyRing.getSigningEntity()
@@ -246,6 +515,87 @@ func (keyRing *KeyRing) SignDetachedStream(message Reader) (*PGPSignature, error
 	return NewPGPSignature(outBuf.Bytes()), nil
 }
 
+// SignDetachedStreamWithKeyID generates and returns a PGPSignature for a given
+// message Reader, made with the unlocked key, or subkey, of the keyring with
+// the given key ID instead of the first signing key.
+// The key must be able to sign.
+func (keyRing *KeyRing) SignDetachedStreamWithKeyID(message Reader, keyID uint64) (*PGPSignature, error) {
+	signEntity, err := keyRing.getSigningEntityByKeyID(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator(), SigningKeyId: keyID}
+	var outBuf bytes.Buffer
+	// sign bin
+	if err := openpgp.DetachSign(&outBuf, signEntity, message, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+
+	return NewPGPSignature(outBuf.Bytes()), nil
+}
+
+// DetachedSigner computes a detached signature over data written in chunks,
+// for when the data is not available as a single Reader.
+type DetachedSigner struct {
+	pipe      *io.PipeWriter
+	done      sync.WaitGroup
+	signature *PGPSignature
+	err       error
+}
+
+// NewDetachedSigner creates a DetachedSigner signing with the first signing
+// key of the keyring, like SignDetachedStream.
+// If canonicalizeText is true, the data is signed as text: its line endings
+// are canonicalized while it is hashed, as required to verify it on any
+// platform. Otherwise the data is signed as is.
+// Finish must be called once all the data has been written.
+func NewDetachedSigner(keyRing *KeyRing, canonicalizeText bool) (*DetachedSigner, error) {
+	signEntity, err := keyRing.getSigningEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator()}
+	detachSign := openpgp.DetachSign
+	if canonicalizeText {
+		detachSign = openpgp.DetachSignText
+	}
+
+	reader, writer := io.Pipe()
+	signer := &DetachedSigner{pipe: writer}
+	signer.done.Add(1)
+
+	go func() {
+		defer signer.done.Done()
+		var outBuf bytes.Buffer
+		if err := detachSign(&outBuf, signEntity, reader, config); err != nil {
+			signer.err = errors.Wrap(err, "gopenpgp: error in signing")
+			_ = reader.CloseWithError(signer.err)
+			return
+		}
+		signer.signature = NewPGPSignature(outBuf.Bytes())
+	}()
+
+	return signer, nil
+}
+
+// Write hashes a chunk of the data to sign.
+// Makes DetachedSigner implement the Writer interface.
+func (signer *DetachedSigner) Write(b []byte) (n int, err error) {
+	return signer.pipe.Write(b)
+}
+
+// Finish ends the data and returns the detached signature.
+func (signer *DetachedSigner) Finish() (*PGPSignature, error) {
+	if err := signer.pipe.Close(); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to close pipe")
+	}
+
+	signer.done.Wait()
+	return signer.signature, signer.err
+}
+
 // VerifyDetachedStream verifies a message reader with a detached PGPSignature
 // and returns a SignatureVerificationError if fails.
 func (keyRing *KeyRing) VerifyDetachedStream(
@@ -261,6 +611,24 @@ func (keyRing *KeyRing) VerifyDetachedStream(
 	)
 }
 
+// SignDetachedStreamWithContext generates and returns a PGPSignature for a
+// given message Reader, bound to the given context like SignDetachedWithContext.
+func (keyRing *KeyRing) SignDetachedStreamWithContext(message Reader, context string) (*PGPSignature, error) {
+	return keyRing.SignDetachedStream(withSigningContext(context, message))
+}
+
+// VerifyDetachedStreamWithContext verifies a message reader with a detached
+// PGPSignature generated for the same context, like VerifyDetachedWithContext,
+// and returns a SignatureVerificationError if fails.
+func (keyRing *KeyRing) VerifyDetachedStreamWithContext(
+	message Reader,
+	signature *PGPSignature,
+	context string,
+	verifyTime int64,
+) error {
+	return keyRing.VerifyDetachedStream(withSigningContext(context, message), signature, verifyTime)
+}
+
 // SignDetachedEncryptedStream generates and returns a PGPMessage
 // containing an encrypted detached signature for a given message Reader.
 func (keyRing *KeyRing) SignDetachedEncryptedStream(
@@ -298,3 +666,53 @@ func (keyRing *KeyRing) VerifyDetachedEncryptedStream(
 	signature := NewPGPSignature(plainMessage.GetBinary())
 	return keyRing.VerifyDetachedStream(message, signature, verifyTime)
 }
+
+// findHiddenRecipient reads the session key packets of a message and trial
+// decrypts the anonymous ones with the decryption keys of the keyring.
+// It returns the recovered session key, the key that decrypted it and the
+// data packet of the message. If there is no anonymous session key packet
+// the keyring can decrypt, or a session key packet is addressed to a key of
+// the keyring, a nil session key is returned.
+func (keyRing *KeyRing) findHiddenRecipient(packets *packet.Reader) (
+	sessionKey *SessionKey, decryptionKey openpgp.Key, dataPacket packet.Packet, err error,
+) {
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return nil, openpgp.Key{}, nil, errors.Wrap(err, "gopenpgp: error in reading message")
+		}
+
+		switch p := p.(type) {
+		case *packet.EncryptedKey:
+			if p.KeyId != 0 {
+				if len(keyRing.entities.KeysById(p.KeyId)) > 0 {
+					return nil, openpgp.Key{}, nil, nil
+				}
+				continue
+			}
+			if sessionKey != nil {
+				continue
+			}
+
+			for _, key := range keyRing.entities.DecryptionKeys() {
+				if key.PrivateKey.Encrypted {
+					continue
+				}
+				if err := p.Decrypt(key.PrivateKey, nil); err == nil {
+					sessionKey, err = newSessionKeyFromEncrypted(p)
+					if err != nil {
+						return nil, openpgp.Key{}, nil, err
+					}
+					decryptionKey = key
+					break
+				}
+			}
+		case *packet.SymmetricKeyEncrypted:
+			continue
+		case *packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
+			return sessionKey, decryptionKey, p, nil
+		default:
+			return nil, openpgp.Key{}, nil, nil
+		}
+	}
+}
@@ -3,7 +3,9 @@ package crypto
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -105,6 +107,65 @@ func TestKeyRing_EncryptDecryptStream(t *testing.T) {
 	}
 }
 
+func TestKeyRing_EncryptStreamAuto(t *testing.T) {
+	messageBytes := bytes.Repeat([]byte("Hello World! "), 1000)
+	for contentType, compressed := range map[string]bool{
+		"text/plain; charset=utf-8": true,
+		"application/json":          true,
+		"image/jpeg":                false,
+		"application/zip":           false,
+	} {
+		var ciphertextBuf bytes.Buffer
+		messageWriter, err := keyRingTestPublic.EncryptStreamAuto(&ciphertextBuf, testMeta, keyRingTestPrivate, contentType)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting stream with key ring, got:", err)
+		}
+		if _, err = messageWriter.Write(messageBytes); err != nil {
+			t.Fatal("Expected no error while writing data, got:", err)
+		}
+		if err = messageWriter.Close(); err != nil {
+			t.Fatal("Expected no error while closing plaintext writer, got:", err)
+		}
+
+		if compressed != (ciphertextBuf.Len() < len(messageBytes)) {
+			t.Fatalf("Expected compression to be %v for %s, got a ciphertext of %d bytes", compressed, contentType, ciphertextBuf.Len())
+		}
+
+		decrypted, err := keyRingTestPrivate.Decrypt(NewPGPMessage(ciphertextBuf.Bytes()), keyRingTestPublic, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		if !bytes.Equal(decrypted.GetBinary(), messageBytes) {
+			t.Fatal("Expected the decrypted data to match the plaintext")
+		}
+	}
+}
+
+func TestIsCompressibleContentType(t *testing.T) {
+	for contentType, compressible := range map[string]bool{
+		"text/html":                 true,
+		"TEXT/PLAIN; charset=utf-8": true,
+		"application/ld+json":       true,
+		"image/svg+xml":             true,
+		"image/png":                 false,
+		"video/mp4":                 false,
+		"application/octet-stream":  false,
+		"":                          false,
+	} {
+		if IsCompressibleContentType(contentType) != compressible {
+			t.Fatalf("Expected %q compressible to be %v", contentType, compressible)
+		}
+	}
+
+	CompressibleContentTypes["application/pdf"] = true
+	CompressibleContentTypes["text/csv"] = false
+	defer delete(CompressibleContentTypes, "application/pdf")
+	defer delete(CompressibleContentTypes, "text/csv")
+	if !IsCompressibleContentType("application/pdf") || IsCompressibleContentType("text/csv") {
+		t.Fatal("Expected CompressibleContentTypes to override the content types")
+	}
+}
+
 func TestKeyRing_EncryptStreamCompatible(t *testing.T) {
 	messageBytes := []byte("Hello World!")
 	messageReader := bytes.NewReader(messageBytes)
@@ -426,6 +487,44 @@ func TestKeyRing_SignDetachedStreamCompatible(t *testing.T) {
 	}
 }
 
+func TestDetachedSigner(t *testing.T) {
+	chunks := []string{"Hello", " World!\n", "Second line\n"}
+	for _, canonicalizeText := range []bool{false, true} {
+		signer, err := NewDetachedSigner(keyRingTestPrivate, canonicalizeText)
+		if err != nil {
+			t.Fatal("Expected no error while creating the signer, got:", err)
+		}
+		for _, chunk := range chunks {
+			if _, err = signer.Write([]byte(chunk)); err != nil {
+				t.Fatal("Expected no error while writing the message, got:", err)
+			}
+		}
+		signature, err := signer.Finish()
+		if err != nil {
+			t.Fatal("Expected no error while signing the message, got:", err)
+		}
+
+		message := NewPlainMessage([]byte(strings.Join(chunks, "")))
+		err = keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while verifying the detached signature, got:", err)
+		}
+
+		crlfMessage := NewPlainMessage([]byte("Hello World!\r\nSecond line\r\n"))
+		err = keyRingTestPublic.VerifyDetached(crlfMessage, signature, GetUnixTime())
+		if canonicalizeText && err != nil {
+			t.Fatal("Expected no error while verifying the text signature with CRLF line endings, got:", err)
+		}
+		if !canonicalizeText && err == nil {
+			t.Fatal("Expected an error while verifying the binary signature with CRLF line endings")
+		}
+	}
+
+	if _, err := NewDetachedSigner(keyRingTestPublic, false); err == nil {
+		t.Fatal("Expected an error while creating a signer without private key")
+	}
+}
+
 func TestKeyRing_VerifyDetachedStreamCompatible(t *testing.T) {
 	messageBytes := []byte("Hello World!")
 	messageReader := bytes.NewReader(messageBytes)
@@ -489,3 +588,214 @@ func TestKeyRing_VerifyDetachedEncryptedStreamCompatible(t *testing.T) {
 		t.Fatal("Expected no error while verifying the detached signature, got:", err)
 	}
 }
+
+func TestKeyRing_PeekFileName(t *testing.T) {
+	messageBytes := []byte("Hello World!")
+	ciphertext, err := keyRingTestPublic.Encrypt(
+		NewPlainMessageFromFile(messageBytes, testMeta.Filename, uint32(testMeta.ModTime)),
+		nil,
+	)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with key ring, got:", err)
+	}
+
+	filename, isBinary, err := keyRingTestPrivate.PeekFileName(bytes.NewReader(ciphertext.GetBinary()))
+	if err != nil {
+		t.Fatal("Expected no error while peeking file name, got:", err)
+	}
+	if filename != testMeta.Filename {
+		t.Fatalf("Expected filename to be %s got %s", testMeta.Filename, filename)
+	}
+	if !isBinary {
+		t.Fatal("Expected message to be binary")
+	}
+
+	_, _, err = keyRingTestPublic.PeekFileName(bytes.NewReader(ciphertext.GetBinary()))
+	if err == nil {
+		t.Fatal("Expected an error while peeking file name without private key")
+	}
+}
+
+func TestKeyRing_DecryptHidden(t *testing.T) {
+	messageBytes := []byte("Hello World!")
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessage(messageBytes), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with key ring, got:", err)
+	}
+
+	// Replace the recipient key ID of the session key packets with the anonymous key ID
+	hidden := clone(ciphertext.GetBinary())
+	for offset := 0; hidden[offset]&0x3f == 1; {
+		var length, headerLength int
+		switch {
+		case hidden[offset+1] < 192:
+			length, headerLength = int(hidden[offset+1]), 2
+		case hidden[offset+1] < 224:
+			length, headerLength = (int(hidden[offset+1])-192)<<8+int(hidden[offset+2])+192, 3
+		default:
+			t.Fatal("Unexpected session key packet length")
+		}
+		copy(hidden[offset+headerLength+1:offset+headerLength+9], make([]byte, 8))
+		offset += headerLength + length
+	}
+
+	ids, ok := NewPGPMessage(hidden).GetEncryptionKeyIDs()
+	if !ok || len(ids) != 1 || ids[0] != 0 {
+		t.Fatal("Expected the message to be encrypted to an anonymous recipient")
+	}
+
+	decryptedReader, err := keyRingTestMultiple.DecryptHidden(
+		bytes.NewReader(hidden),
+		keyRingTestPublic,
+		GetUnixTime(),
+	)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting hidden recipient message, got:", err)
+	}
+
+	decryptedBytes, err := io.ReadAll(decryptedReader)
+	if err != nil {
+		t.Fatal("Expected no error while reading the decrypted data, got:", err)
+	}
+	if !bytes.Equal(decryptedBytes, messageBytes) {
+		t.Fatalf("Expected the decrypted data to be %s got %s", string(messageBytes), string(decryptedBytes))
+	}
+
+	err = decryptedReader.VerifySignature()
+	if err != nil {
+		t.Fatal("Expected no error while verifying the signature, got:", err)
+	}
+
+	decryptionKey, _ := keyRingTestPrivate.entities[0].EncryptionKey(getNow())
+	if decryptedReader.GetDecryptionKeyID() != decryptionKey.PublicKey.KeyId {
+		t.Fatal("Expected the message to be decrypted with the encryption key of the recipient")
+	}
+
+	// Messages to known recipients are decrypted as usual
+	decryptedReader, err = keyRingTestPrivate.DecryptHidden(
+		bytes.NewReader(ciphertext.GetBinary()),
+		keyRingTestPublic,
+		GetUnixTime(),
+	)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting message, got:", err)
+	}
+	decryptedBytes, err = io.ReadAll(decryptedReader)
+	if err != nil {
+		t.Fatal("Expected no error while reading the decrypted data, got:", err)
+	}
+	if !bytes.Equal(decryptedBytes, messageBytes) {
+		t.Fatalf("Expected the decrypted data to be %s got %s", string(messageBytes), string(decryptedBytes))
+	}
+}
+
+func TestKeyRing_DecryptSecureStream(t *testing.T) {
+	messageBytes := []byte("Hello World!")
+	signed, err := keyRingTestPublic.Encrypt(NewPlainMessage(messageBytes), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting the message, got:", err)
+	}
+
+	reader, err := keyRingTestPrivate.DecryptSecureStream(bytes.NewReader(signed.GetBinary()), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting the message, got:", err)
+	}
+	// Partial reads must not be reported as successful
+	if _, err = reader.Read(make([]byte, 1)); err != nil {
+		t.Fatal("Expected no error while reading the message, got:", err)
+	}
+	if err = reader.Close(); !errors.Is(err, ErrMessageNotFullyRead) {
+		t.Fatal("Expected ErrMessageNotFullyRead after a partial read, got:", err)
+	}
+
+	reader, err = keyRingTestPrivate.DecryptSecureStream(bytes.NewReader(signed.GetBinary()), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting the message, got:", err)
+	}
+	decryptedBytes, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading the message, got:", err)
+	}
+	if !bytes.Equal(decryptedBytes, messageBytes) {
+		t.Fatalf("Expected the decrypted data to be %s got %s", string(messageBytes), string(decryptedBytes))
+	}
+	if err = reader.Close(); err != nil {
+		t.Fatal("Expected no error while closing the reader, got:", err)
+	}
+
+	unsigned, err := keyRingTestPublic.Encrypt(NewPlainMessage(messageBytes), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting the message, got:", err)
+	}
+	reader, err = keyRingTestPrivate.DecryptSecureStream(bytes.NewReader(unsigned.GetBinary()), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting the message, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading the message, got:", err)
+	}
+	if err = reader.Close(); !errors.As(err, &SignatureVerificationError{}) {
+		t.Fatal("Expected a SignatureVerificationError for an unsigned message, got:", err)
+	}
+
+	tampered := clone(signed.GetBinary())
+	tampered[len(tampered)-1] ^= 1
+	reader, err = keyRingTestPrivate.DecryptSecureStream(bytes.NewReader(tampered), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting the message, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); !errors.Is(err, ErrIntegrityCheckFailed) {
+		t.Fatal("Expected ErrIntegrityCheckFailed while reading a tampered message, got:", err)
+	}
+	if err = reader.Close(); !errors.Is(err, ErrIntegrityCheckFailed) {
+		t.Fatal("Expected ErrIntegrityCheckFailed while closing the reader, got:", err)
+	}
+
+	if _, err = keyRingTestPrivate.DecryptSecureStream(bytes.NewReader(signed.GetBinary()), nil, GetUnixTime()); err == nil {
+		t.Fatal("Expected an error without verification keyring")
+	}
+}
+
+func TestKeyRing_GetSigningKeyFingerprint(t *testing.T) {
+	messageBytes := []byte("Hello World!")
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessage(messageBytes), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting the message, got:", err)
+	}
+
+	decryptedReader, err := keyRingTestPrivate.DecryptStream(bytes.NewReader(ciphertext.GetBinary()), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting the message, got:", err)
+	}
+	if _, err = decryptedReader.GetSigningKeyFingerprint(); err == nil {
+		t.Fatal("Expected an error before the message is read entirely")
+	}
+	if _, err = io.Copy(ioutil.Discard, decryptedReader); err != nil {
+		t.Fatal("Expected no error while reading the message, got:", err)
+	}
+	fingerprint, err := decryptedReader.GetSigningKeyFingerprint()
+	if err != nil {
+		t.Fatal("Expected no error while getting the signing key fingerprint, got:", err)
+	}
+	if expected := keyRingTestPublic.GetKeys()[0].GetFingerprint(); fingerprint != expected {
+		t.Fatalf("Expected the signing key fingerprint to be %s got %s", expected, fingerprint)
+	}
+	if err = decryptedReader.VerifySignature(); err != nil {
+		t.Fatal("Expected no error while verifying the signature, got:", err)
+	}
+
+	unsigned, err := keyRingTestPublic.Encrypt(NewPlainMessage(messageBytes), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting the message, got:", err)
+	}
+	decryptedReader, err = keyRingTestPrivate.DecryptStream(bytes.NewReader(unsigned.GetBinary()), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting the message, got:", err)
+	}
+	if _, err = io.Copy(ioutil.Discard, decryptedReader); err != nil {
+		t.Fatal("Expected no error while reading the message, got:", err)
+	}
+	if _, err = decryptedReader.GetSigningKeyFingerprint(); err == nil {
+		t.Fatal("Expected an error for an unsigned message")
+	}
+}
@@ -1,14 +1,23 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/dsa"
 	"crypto/ed25519"
 	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"math/big"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/ecdh"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/ProtonMail/gopenpgp/v2/constants"
 )
 
@@ -94,6 +103,112 @@ func TestIdentities(t *testing.T) {
 	assert.Exactly(t, identities[0], testIdentity)
 }
 
+func TestGetPrimaryIdentity(t *testing.T) {
+	assert.Exactly(t, testIdentity, keyRingTestPrivate.GetPrimaryIdentity())
+	assert.Nil(t, (&KeyRing{}).GetPrimaryIdentity())
+
+	primaryIdentity := &Identity{Name: "Zoe", Email: "zoe@example.com"}
+	otherIdentity := &Identity{Name: "Alice", Email: "alice@example.com"}
+	key, err := GenerateKeyWithIdentities([]*Identity{primaryIdentity, otherIdentity}, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Exactly(t, primaryIdentity, keyRing.GetPrimaryIdentity())
+
+	for _, id := range key.entity.Identities {
+		id.SelfSignature.IsPrimaryId = nil
+	}
+	assert.Exactly(t, otherIdentity, keyRing.GetPrimaryIdentity())
+}
+
+func TestAddIdentity(t *testing.T) {
+	keyRing, err := keyRingTestPrivate.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	alias := &Identity{Name: "Alias", Email: "alias@example.com"}
+	if err = keyRing.AddIdentity(alias.Name, alias.Email); err != nil {
+		t.Fatal("Expected no error while adding identity, got:", err)
+	}
+	assert.Contains(t, keyRing.GetIdentities(), alias)
+	assert.Exactly(t, testIdentity, keyRing.GetPrimaryIdentity())
+	assert.NotNil(t, keyRing.AddIdentity(alias.Name, alias.Email))
+
+	armored, err := keyRing.GetArmoredPublicKeys()
+	if err != nil {
+		t.Fatal("Expected no error while armoring public keys, got:", err)
+	}
+	publicKey, err := NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading public key, got:", err)
+	}
+	publicKeyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Len(t, publicKeyRing.GetIdentities(), 2)
+	assert.Contains(t, publicKeyRing.GetIdentities(), alias)
+	assert.Exactly(t, testIdentity, publicKeyRing.GetPrimaryIdentity())
+
+	assert.NotNil(t, publicKeyRing.AddIdentity("Other", "other@example.com"))
+	assert.NotNil(t, (&KeyRing{}).AddIdentity("Other", "other@example.com"))
+}
+
+func TestRemoveIdentity(t *testing.T) {
+	key, err := GenerateKeyWithIdentities([]*Identity{
+		{Name: "Max Mustermann", Email: "max.mustermann@work.example"},
+		{Name: "Max", Email: "max@home.example"},
+	}, "x25519", 256)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+
+	publicKey, err := key.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	publicKeyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building public keyring, got:", err)
+	}
+	assert.NotNil(t, publicKeyRing.RemoveIdentity("max.mustermann@work.example"))
+	assert.Len(t, publicKeyRing.GetIdentities(), 2)
+
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = keyRing.RemoveIdentity("Max.Mustermann@work.example"); err != nil {
+		t.Fatal("Expected no error while removing identity, got:", err)
+	}
+	assert.Exactly(t, []*Identity{{Name: "Max", Email: "max@home.example"}}, keyRing.GetIdentities())
+	// The stale self-signature is replaced
+	newPrimary := keyRing.entities[0].PrimaryIdentity()
+	assert.Exactly(t, []*packet.Signature{newPrimary.SelfSignature}, newPrimary.Signatures)
+
+	assert.Exactly(t, ErrLastIdentity, keyRing.RemoveIdentity("max@home.example"))
+	assert.Len(t, keyRing.GetIdentities(), 1)
+
+	// The remaining identity is the primary one once serialized
+	armored, err := key.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	parsedKey, err := NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	assert.Len(t, parsedKey.entity.Identities, 1)
+	assert.Len(t, parsedKey.entity.PrimaryIdentity().Signatures, 1)
+	primarySignature := parsedKey.entity.PrimaryIdentity().SelfSignature
+	assert.NotNil(t, primarySignature.IsPrimaryId)
+	assert.True(t, *primarySignature.IsPrimaryId)
+}
+
 func TestFilterExpiredKeys(t *testing.T) {
 	expiredKey, err := NewKeyFromArmored(readTestFile("key_expiredKey", false))
 	if err != nil {
@@ -116,12 +231,621 @@ func TestFilterExpiredKeys(t *testing.T) {
 	assert.Exactly(t, unexpired[0].GetKeyIDs(), keyRingTestPrivate.GetKeyIDs())
 }
 
+func TestKeyRingErrors(t *testing.T) {
+	expiredKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	lifetime := uint32(1)
+	expiredKeyRing.entities[0].Subkeys[0].Sig.KeyLifetimeSecs = &lifetime
+	_, err = FilterExpiredKeys([]*KeyRing{expiredKeyRing})
+	assert.True(t, errors.Is(err, ErrAllKeysExpired))
+
+	_, err = keyRingTestPublic.SignDetached(NewPlainMessageFromString("data"))
+	assert.Exactly(t, ErrKeyringNotUnlocked, err)
+
+	_, err = keyRingTestPublic.ExportPrivateWithS2K(testMailboxPassword, 65536, constants.AES256)
+	assert.True(t, errors.Is(err, ErrKeyringNotUnlocked))
+
+	emptyKeyRing, err := NewKeyRing(nil)
+	if err != nil {
+		t.Fatal("Expected no error while building empty keyring, got:", err)
+	}
+	_, err = emptyKeyRing.FirstKey()
+	assert.True(t, errors.Is(err, ErrNoKeyInKeyring))
+
+	_, err = emptyKeyRing.ExpiresWithin(time.Hour, time.Now())
+	assert.True(t, errors.Is(err, ErrNoKeyInKeyring))
+
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	_, err = emptyKeyRing.EncryptSessionKey(sessionKey)
+	assert.True(t, errors.Is(err, ErrNoKeyInKeyring))
+}
+
+func TestKeyRingExpiration(t *testing.T) {
+	expiredKey, err := NewKeyFromArmored(readTestFile("key_expiredKey", false))
+	if err != nil {
+		t.Fatal("Cannot unarmor expired key:", err)
+	}
+	expiredKeyRing, err := NewKeyRing(expiredKey)
+	if err != nil {
+		t.Fatal("Cannot create keyring with expired key:", err)
+	}
+
+	assert.True(t, expiredKeyRing.IsExpired())
+	assert.False(t, keyRingTestPrivate.IsExpired())
+
+	_, ok := keyRingTestPrivate.EarliestExpiration()
+	assert.False(t, ok)
+
+	entity, err := openpgp.NewEntity("expiring", "", "expiring@example.com", &packet.Config{
+		Algorithm:       packet.PubKeyAlgoEdDSA,
+		Time:            getTimeGenerator(),
+		KeyLifetimeSecs: 24 * 60 * 60,
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating expiring key, got:", err)
+	}
+	expiringKeyRing := &KeyRing{entities: openpgp.EntityList{entity, keyRingTestPrivate.entities[0]}}
+	assert.False(t, expiringKeyRing.IsExpired())
+	expiration, ok := expiringKeyRing.EarliestExpiration()
+	assert.True(t, ok)
+	assert.Exactly(t, entity.PrimaryKey.CreationTime.Add(24*time.Hour).Unix(), expiration.Unix())
+}
+
+func TestExpiresWithin(t *testing.T) {
+	now := getNow()
+	entity, err := openpgp.NewEntity("expiring", "", "expiring@example.com", &packet.Config{
+		Algorithm:       packet.PubKeyAlgoEdDSA,
+		Time:            getTimeGenerator(),
+		KeyLifetimeSecs: 24 * 60 * 60,
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating expiring key, got:", err)
+	}
+
+	expiringKeyRing := &KeyRing{entities: openpgp.EntityList{entity}}
+
+	expiring, err := expiringKeyRing.ExpiresWithin(48*time.Hour, now)
+	if err != nil {
+		t.Fatal("Expected no error while checking expiration, got:", err)
+	}
+	assert.True(t, expiring)
+
+	expiring, err = expiringKeyRing.ExpiresWithin(time.Hour, now)
+	if err != nil {
+		t.Fatal("Expected no error while checking expiration, got:", err)
+	}
+	assert.False(t, expiring)
+
+	expiring, err = expiringKeyRing.ExpiresWithin(48*time.Hour, now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal("Expected no error while checking expiration, got:", err)
+	}
+	assert.False(t, expiring)
+
+	expiring, err = keyRingTestMultiple.ExpiresWithin(48*time.Hour, now)
+	if err != nil {
+		t.Fatal("Expected no error while checking expiration, got:", err)
+	}
+	assert.False(t, expiring)
+
+	if err = entity.RevokeKey(packet.KeyCompromised, "", nil); err != nil {
+		t.Fatal("Expected no error while revoking key, got:", err)
+	}
+
+	expiring, err = expiringKeyRing.ExpiresWithin(48*time.Hour, now)
+	if err != nil {
+		t.Fatal("Expected no error while checking expiration, got:", err)
+	}
+	assert.False(t, expiring)
+
+	_, err = (&KeyRing{}).ExpiresWithin(time.Hour, now)
+	assert.NotNil(t, err)
+}
+
+func TestExportBundle(t *testing.T) {
+	bundle, err := keyRingTestMultiple.ExportBundle()
+	if err != nil {
+		t.Fatal("Expected no error while exporting bundle, got:", err)
+	}
+
+	assert.Len(t, bundle, 3)
+	assert.Exactly(t, "rsa", bundle[0].Algorithm)
+	assert.Exactly(t, "eddsa", bundle[1].Algorithm)
+	assert.Exactly(t, keyTestEC.GetFingerprint(), bundle[1].Fingerprint)
+	assert.Exactly(t, []KeyBundleIdentity{{Name: keyTestName, Email: keyTestDomain}}, bundle[1].Identities)
+	assert.Exactly(t, int64(0), bundle[1].Expiration)
+
+	for _, entry := range bundle {
+		publicKey, err := NewKeyFromArmored(entry.ArmoredPublicKey)
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring exported public key, got:", err)
+		}
+		assert.False(t, publicKey.IsPrivate())
+		assert.Exactly(t, entry.Fingerprint, publicKey.GetFingerprint())
+	}
+}
+
+func TestExportBundleIdentities(t *testing.T) {
+	keyRing, err := keyRingTestPrivate.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	for _, alias := range []*Identity{{Name: "Zed", Email: "zed@example.com"}, {Name: "Alice", Email: "alice@example.com"}} {
+		if err = keyRing.AddIdentity(alias.Name, alias.Email); err != nil {
+			t.Fatal("Expected no error while adding identity, got:", err)
+		}
+	}
+
+	bundle, err := keyRing.ExportBundle()
+	if err != nil {
+		t.Fatal("Expected no error while exporting bundle, got:", err)
+	}
+	assert.Exactly(t, []KeyBundleIdentity{
+		{Name: testIdentity.Name, Email: testIdentity.Email},
+		{Name: "Alice", Email: "alice@example.com"},
+		{Name: "Zed", Email: "zed@example.com"},
+	}, bundle[0].Identities)
+
+	serialized, err := json.Marshal(bundle[0].Identities[1])
+	if err != nil {
+		t.Fatal("Expected no error while serializing identity, got:", err)
+	}
+	assert.Exactly(t, `{"name":"Alice","email":"alice@example.com"}`, string(serialized))
+}
+
+func TestPublicKeyParameters(t *testing.T) {
+	keyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = keyRing.AddKey(keyTestRSA); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	params, err := keyRing.PublicKeyParameters()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key parameters, got:", err)
+	}
+
+	assert.Len(t, params, 4)
+
+	ecEntity := keyTestEC.entity
+	assert.Exactly(t, ecEntity.PrimaryKey.KeyId, params[0].KeyID)
+	assert.Exactly(t, "eddsa", params[0].Algorithm)
+	assert.Exactly(t, "Ed25519", params[0].Curve)
+	assert.Exactly(t, []byte(*ecEntity.PrimaryKey.PublicKey.(*ed25519.PublicKey)), params[0].Point)
+
+	assert.Exactly(t, ecEntity.Subkeys[0].PublicKey.KeyId, params[1].KeyID)
+	assert.Exactly(t, "ecdh", params[1].Algorithm)
+	assert.Exactly(t, "Curve25519", params[1].Curve)
+	assert.Len(t, params[1].Point, 32)
+	assert.Exactly(t, ecEntity.Subkeys[0].PublicKey.PublicKey.(*ecdh.PublicKey).X.Bytes()[1:], params[1].Point)
+
+	rsaKey := keyTestRSA.entity.PrimaryKey.PublicKey.(*rsa.PublicKey)
+	assert.Exactly(t, "rsa", params[2].Algorithm)
+	assert.Exactly(t, rsaKey.N.Bytes(), params[2].N)
+	assert.Exactly(t, []byte{0x01, 0x00, 0x01}, params[2].E)
+	assert.Nil(t, params[2].Point)
+}
+
+func TestSubkeyInfo(t *testing.T) {
+	keyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = keyRing.AddKey(keyTestRSA); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	details := keyRing.SubkeyInfo()
+	assert.Len(t, details, 4)
+
+	ecEntity := keyTestEC.entity
+	assert.Exactly(t, ecEntity.PrimaryKey.KeyId, details[0].KeyID)
+	assert.True(t, details[0].IsPrimary)
+	assert.Exactly(t, "eddsa", details[0].Algorithm)
+	assert.Exactly(t, 255, details[0].BitLength)
+	assert.Exactly(t, ecEntity.PrimaryKey.CreationTime.Unix(), details[0].CreationTime)
+	assert.True(t, details[0].CanSign)
+	assert.False(t, details[0].CanEncrypt)
+
+	assert.Exactly(t, ecEntity.Subkeys[0].PublicKey.KeyId, details[1].KeyID)
+	assert.False(t, details[1].IsPrimary)
+	assert.Exactly(t, "ecdh", details[1].Algorithm)
+	assert.Exactly(t, 255, details[1].BitLength)
+	assert.False(t, details[1].CanSign)
+	assert.True(t, details[1].CanEncrypt)
+
+	assert.True(t, details[2].IsPrimary)
+	assert.Exactly(t, "rsa", details[2].Algorithm)
+	assert.Exactly(t, keyTestRSA.entity.PrimaryKey.PublicKey.(*rsa.PublicKey).N.BitLen(), details[2].BitLength)
+
+	for _, keyDetails := range details {
+		assert.Exactly(t, int64(0), keyDetails.Expiration)
+	}
+
+	entity, err := openpgp.NewEntity("expiring", "", "expiring@example.com", &packet.Config{
+		Time:            getKeyGenerationTimeGenerator(),
+		KeyLifetimeSecs: 3600,
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	details = (&KeyRing{entities: openpgp.EntityList{entity}}).SubkeyInfo()
+	assert.Len(t, details, 2)
+	assert.Exactly(t, entity.PrimaryKey.CreationTime.Unix()+3600, details[0].Expiration)
+}
+
+func TestKeyInfo(t *testing.T) {
+	info := keyRingTestMultiple.KeyInfo()
+	assert.Len(t, info, len(keyRingTestMultiple.entities))
+
+	for i, key := range keyRingTestMultiple.GetKeys() {
+		assert.Exactly(t, key.GetKeyID(), info[i].KeyID)
+		assert.Exactly(t, key.GetFingerprint(), info[i].Fingerprint)
+		assert.Exactly(t, key.entity.PrimaryKey.CreationTime.Unix(), info[i].CreationTime)
+		assert.True(t, info[i].IsPrivate)
+	}
+
+	publicInfo := keyRingTestPublic.KeyInfo()
+	assert.Len(t, publicInfo, 1)
+	assert.Exactly(t, "rsa", publicInfo[0].Algorithm)
+	assert.Exactly(t, 2048, publicInfo[0].BitLength)
+	assert.False(t, publicInfo[0].IsPrivate)
+}
+
+func TestCheckKeyStrength(t *testing.T) {
+	assert.Nil(t, keyRingTestPublic.CheckKeyStrength(2048))
+
+	err := keyRingTestPublic.CheckKeyStrength(4096)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), keyIDToHex(keyRingTestPublic.entities[0].PrimaryKey.KeyId))
+
+	weakKeyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.NotNil(t, weakKeyRing.CheckKeyStrength(2048))
+	assert.Nil(t, weakKeyRing.CheckKeyStrength(1024))
+
+	dsaKey := packet.NewDSAPublicKey(getNow(), &dsa.PublicKey{
+		Parameters: dsa.Parameters{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(4)},
+		Y:          big.NewInt(8),
+	})
+	dsaKeyRing := &KeyRing{entities: openpgp.EntityList{{PrimaryKey: dsaKey}}}
+	err = dsaKeyRing.CheckKeyStrength(2048)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "dsa")
+}
+
+func TestExportPrivateWithS2K(t *testing.T) {
+	passphrase := []byte("backup passphrase")
+	armored, err := keyRingTestMultiple.ExportPrivateWithS2K(passphrase, 65011712, constants.AES128)
+	if err != nil {
+		t.Fatal("Expected no error while exporting private keys, got:", err)
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		t.Fatal("Expected no error while reading exported keys, got:", err)
+	}
+	assert.Len(t, entities, keyRingTestMultiple.CountEntities())
+
+	for i, e := range entities {
+		lockedKey := &Key{e}
+		assert.Exactly(t, keyRingTestMultiple.entities[i].PrimaryKey.Fingerprint, e.PrimaryKey.Fingerprint)
+
+		locked, err := lockedKey.IsLocked()
+		if err != nil {
+			t.Fatal("Expected no error while checking key, got:", err)
+		}
+		assert.True(t, locked)
+
+		_, err = lockedKey.Unlock([]byte("wrong passphrase"))
+		assert.NotNil(t, err)
+
+		unlockedKey, err := lockedKey.Unlock(passphrase)
+		if err != nil {
+			t.Fatal("Expected no error while unlocking exported key, got:", err)
+		}
+		unlockedKeyRing, err := NewKeyRing(unlockedKey)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		if err = unlockedKeyRing.SelfTest(nil); err != nil {
+			t.Fatal("Expected no error while self-testing exported key, got:", err)
+		}
+	}
+
+	_, err = keyRingTestMultiple.ExportPrivateWithS2K(passphrase, 65536, constants.CAST5)
+	assert.NotNil(t, err)
+
+	_, err = keyRingTestMultiple.ExportPrivateWithS2K(passphrase, 1024, constants.AES256)
+	assert.NotNil(t, err)
+
+	_, err = keyRingTestPublic.ExportPrivateWithS2K(passphrase, 65536, constants.AES256)
+	assert.NotNil(t, err)
+}
+
+func TestNewKeyRingWithPassphrases(t *testing.T) {
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	otherLockedKey, err := keyTestEC.Lock([]byte("other passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+
+	keys := []*Key{lockedKey, otherLockedKey, publicKey}
+	passphrases := map[string][]byte{lockedKey.GetFingerprint(): testMailboxPassword}
+	keyRing, lockedKeys, err := NewKeyRingWithPassphrases(keys, passphrases)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Exactly(t, []*Key{otherLockedKey}, lockedKeys)
+	assert.Exactly(t, 2, keyRing.CountEntities())
+	assert.Exactly(t, []string{lockedKey.GetFingerprint(), publicKey.GetFingerprint()}, keyRing.GetFingerprints())
+	isUnlocked, err := keyRing.GetKeys()[0].IsUnlocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking key, got:", err)
+	}
+	assert.True(t, isUnlocked)
+
+	isLocked, err := lockedKey.IsLocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking key, got:", err)
+	}
+	assert.True(t, isLocked)
+
+	passphrases[otherLockedKey.GetFingerprint()] = []byte("wrong passphrase")
+	_, _, err = NewKeyRingWithPassphrases(keys, passphrases)
+	assert.NotNil(t, err)
+}
+
+func TestScanKeyRing(t *testing.T) {
+	var keys []byte
+	for _, key := range []*Key{keyTestRSA, keyTestEC, keyTestRSA} {
+		publicKey, err := key.GetPublicKey()
+		if err != nil {
+			t.Fatal("Expected no error while serializing public key, got:", err)
+		}
+		keys = append(keys, publicKey...)
+	}
+
+	keyRing, err := ScanKeyRing(bytes.NewReader(keys), func(fingerprint string) bool {
+		return fingerprint == keyTestEC.GetFingerprint()
+	})
+	if err != nil {
+		t.Fatal("Expected no error while scanning keys, got:", err)
+	}
+	assert.Exactly(t, 1, keyRing.CountEntities())
+	assert.Exactly(t, keyTestEC.GetFingerprint(), keyRing.GetKeys()[0].GetFingerprint())
+	assert.False(t, keyRing.GetKeys()[0].IsPrivate())
+
+	keyRing, err = ScanKeyRing(bytes.NewReader(keys), func(string) bool { return false })
+	if err != nil {
+		t.Fatal("Expected no error while scanning keys, got:", err)
+	}
+	assert.Exactly(t, 0, keyRing.CountEntities())
+
+	keyRing, err = ScanKeyRing(strings.NewReader(readTestFile("keyring_publicKey", false)), func(string) bool { return true })
+	if err != nil {
+		t.Fatal("Expected no error while scanning armored keys, got:", err)
+	}
+	assert.Exactly(t, keyRingTestPublic.GetKeyIDs(), keyRing.GetKeyIDs())
+
+	_, err = ScanKeyRing(strings.NewReader(readTestFile("keyring_privateKey", false)), func(string) bool { return true })
+	assert.NotNil(t, err)
+}
+
+func TestReadLengthPrefixedKeyRings(t *testing.T) {
+	var records []byte
+	for _, keyRing := range []*KeyRing{keyRingTestPublic, keyRingTestMultiple} {
+		var keys []byte
+		for _, key := range keyRing.GetKeys() {
+			publicKey, err := key.GetPublicKey()
+			if err != nil {
+				t.Fatal("Expected no error while serializing public key, got:", err)
+			}
+			keys = append(keys, publicKey...)
+		}
+		records = append(records, byte(len(keys)>>24), byte(len(keys)>>16), byte(len(keys)>>8), byte(len(keys)))
+		records = append(records, keys...)
+	}
+
+	keyRings, err := ReadLengthPrefixedKeyRings(bytes.NewReader(records))
+	if err != nil {
+		t.Fatal("Expected no error while reading key records, got:", err)
+	}
+	assert.Len(t, keyRings, 2)
+	assert.Exactly(t, keyRingTestPublic.GetKeyIDs(), keyRings[0].GetKeyIDs())
+	assert.Exactly(t, keyRingTestMultiple.GetKeyIDs(), keyRings[1].GetKeyIDs())
+
+	keyRings, err = ReadLengthPrefixedKeyRings(bytes.NewReader(nil))
+	if err != nil {
+		t.Fatal("Expected no error while reading empty key records, got:", err)
+	}
+	assert.Len(t, keyRings, 0)
+
+	_, err = ReadLengthPrefixedKeyRings(bytes.NewReader(records[:len(records)-1]))
+	assert.EqualError(t, err, "gopenpgp: key record 1 is truncated")
+
+	_, err = ReadLengthPrefixedKeyRings(bytes.NewReader(append(records, 0, 0)))
+	assert.Contains(t, err.Error(), "key record 2")
+}
+
+func TestIsFullyUnlocked(t *testing.T) {
+	assert.True(t, keyRingTestPrivate.IsFullyUnlocked())
+	assert.True(t, keyRingTestMultiple.IsFullyUnlocked())
+	assert.False(t, keyRingTestPublic.IsFullyUnlocked())
+
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+
+	lockedKeyRing := &KeyRing{entities: append(openpgp.EntityList{lockedKey.entity}, keyRingTestMultiple.entities...)}
+	assert.False(t, lockedKeyRing.IsFullyUnlocked())
+}
+
+func TestGetArmoredPublicKeys(t *testing.T) {
+	armored, err := keyRingTestMultiple.GetArmoredPublicKeys()
+	if err != nil {
+		t.Fatal("Expected no error while armoring public keys, got:", err)
+	}
+
+	blocks := strings.Split(armored, "\n\n-----BEGIN")
+	assert.Len(t, blocks, 3)
+
+	for i, key := range keyRingTestMultiple.GetKeys() {
+		block := blocks[i]
+		if i > 0 {
+			block = "-----BEGIN" + block
+		}
+
+		publicKey, err := NewKeyFromArmored(block)
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring public key, got:", err)
+		}
+		assert.False(t, publicKey.IsPrivate())
+		assert.Exactly(t, key.GetFingerprint(), publicKey.GetFingerprint())
+	}
+}
+
+func TestGetArmoredPublicKeysWithCustomHeaders(t *testing.T) {
+	armored, err := keyRingTestMultiple.GetArmoredPublicKeysWithCustomHeaders("custom comment", "custom version")
+	if err != nil {
+		t.Fatal("Expected no error while armoring public keys, got:", err)
+	}
+	assert.Exactly(t, 3, strings.Count(armored, "Comment: custom comment"))
+	assert.Exactly(t, 3, strings.Count(armored, "Version: custom version"))
+	assert.NotContains(t, armored, constants.ArmorHeaderComment)
+
+	withoutHeaders, err := keyRingTestMultiple.GetArmoredPublicKeysWithCustomHeaders("", "")
+	if err != nil {
+		t.Fatal("Expected no error while armoring public keys, got:", err)
+	}
+	assert.NotContains(t, withoutHeaders, "Comment:")
+	assert.NotContains(t, withoutHeaders, "Version:")
+
+	defaultArmored, err := keyRingTestMultiple.GetArmoredPublicKeys()
+	if err != nil {
+		t.Fatal("Expected no error while armoring public keys, got:", err)
+	}
+	assert.Contains(t, defaultArmored, "Comment: "+constants.ArmorHeaderComment)
+}
+
+func TestDuplicateSubkeys(t *testing.T) {
+	assert.False(t, keyRingTestMultiple.HasDuplicateSubkeys())
+
+	publicKey, err := NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring public key, got:", err)
+	}
+
+	// Serialize the key with its subkey duplicated, and import it back
+	entity := publicKey.entity
+	entity.Subkeys = append(entity.Subkeys, entity.Subkeys[0])
+	var serialized bytes.Buffer
+	if err = entity.Serialize(&serialized); err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+
+	duplicatedKey, err := NewKey(serialized.Bytes())
+	if err != nil {
+		t.Fatal("Expected no error while importing key, got:", err)
+	}
+	assert.Len(t, duplicatedKey.entity.Subkeys, 2)
+
+	keyRing, err := NewKeyRing(duplicatedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.True(t, keyRing.HasDuplicateSubkeys())
+
+	keyRing.DedupSubkeys()
+	assert.False(t, keyRing.HasDuplicateSubkeys())
+	assert.Len(t, keyRing.entities[0].Subkeys, 1)
+	assert.True(t, keyRing.CanEncrypt())
+}
+
 func TestKeyIds(t *testing.T) {
 	keyIDs := keyRingTestPrivate.GetKeyIDs()
 	var assertKeyIDs = []uint64{4518840640391470884}
 	assert.Exactly(t, assertKeyIDs, keyIDs)
 }
 
+func TestKeyRingFingerprints(t *testing.T) {
+	fingerprints := keyRingTestMultiple.GetFingerprints()
+	assert.Len(t, fingerprints, 3)
+	for i, key := range keyRingTestMultiple.GetKeys() {
+		assert.Exactly(t, key.GetFingerprint(), fingerprints[i])
+	}
+	assert.Exactly(t, keyTestEC.GetFingerprint(), fingerprints[1])
+}
+
+func TestGetArmoredPrivateKey(t *testing.T) {
+	subkeyID := keyTestEC.entity.Subkeys[0].PublicKey.KeyId
+	armored, err := keyRingTestMultiple.GetArmoredPrivateKey(subkeyID)
+	if err != nil {
+		t.Fatal("Expected no error while armoring private key, got:", err)
+	}
+
+	key, err := NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading private key, got:", err)
+	}
+	assert.True(t, key.IsPrivate())
+	assert.Exactly(t, keyTestEC.GetFingerprint(), key.GetFingerprint())
+	unlocked, err := key.IsUnlocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking if key is unlocked, got:", err)
+	}
+	assert.True(t, unlocked)
+
+	_, err = keyRingTestPublic.GetArmoredPrivateKey(keyRingTestPublic.GetKeyIDs()[0])
+	assert.NotNil(t, err)
+
+	_, err = keyRingTestMultiple.GetArmoredPrivateKey(0)
+	assert.NotNil(t, err)
+}
+
+func TestKeyIDStrings(t *testing.T) {
+	formats := keyRingTestMultiple.KeyIDStrings()
+	assert.Len(t, formats, 3)
+
+	for i, key := range keyRingTestMultiple.GetKeys() {
+		assert.Exactly(t, key.GetHexKeyID(), formats[i].LongKeyID)
+		assert.Exactly(t, key.GetHexKeyID()[8:], formats[i].ShortKeyID)
+		assert.Exactly(t, key.GetSHA256Fingerprints()[0], formats[i].SHA256Fingerprint)
+		assert.Exactly(t, key.GetFingerprint(), strings.ToLower(strings.ReplaceAll(formats[i].Fingerprint, " ", "")))
+	}
+
+	assert.Exactly(t, "6E8B A229 B0CC CAF6 962F 9795 3EB6 259E DF21 DF24", keyRingTestPublic.KeyIDStrings()[0].Fingerprint)
+}
+
+func TestKeyRingContains(t *testing.T) {
+	subkey := keyTestEC.entity.Subkeys[0].PublicKey
+
+	assert.True(t, keyRingTestMultiple.Contains(keyTestEC.GetKeyID()))
+	assert.True(t, keyRingTestMultiple.Contains(subkey.KeyId))
+	assert.False(t, keyRingTestPublic.Contains(subkey.KeyId))
+
+	assert.True(t, keyRingTestMultiple.ContainsFingerprint(keyTestEC.GetFingerprint()))
+	assert.True(t, keyRingTestMultiple.ContainsFingerprint(strings.ToUpper(hex.EncodeToString(subkey.Fingerprint))))
+	assert.False(t, keyRingTestPublic.ContainsFingerprint(keyTestEC.GetFingerprint()))
+}
+
 func TestMultipleKeyRing(t *testing.T) {
 	assert.Exactly(t, 3, len(keyRingTestMultiple.entities))
 	assert.Exactly(t, 3, keyRingTestMultiple.CountEntities())
@@ -147,6 +871,146 @@ func TestMultipleKeyRing(t *testing.T) {
 	assert.Exactly(t, 1, singleKeyRing.CountDecryptionEntities())
 }
 
+func TestKeyRingMerge(t *testing.T) {
+	keyRing, err := keyRingTestMultiple.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	if err = keyRing.Merge(keyRingTestMultiple); err != nil {
+		t.Fatal("Expected no error while merging keyring, got:", err)
+	}
+	assert.Exactly(t, keyRingTestMultiple.GetKeyIDs(), keyRing.GetKeyIDs())
+
+	publicKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	if err = keyRing.Merge(publicKeyRing); err != nil {
+		t.Fatal("Expected no error while merging keyring, got:", err)
+	}
+	assert.Exactly(t, keyRingTestMultiple.GetKeyIDs(), keyRing.GetKeyIDs())
+
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = keyRing.Merge(ecKeyRing); err != nil {
+		t.Fatal("Expected no error while merging keyring, got:", err)
+	}
+	assert.Exactly(t, keyRingTestMultiple.CountEntities(), keyRing.CountEntities())
+	// The merged keys are copies
+	assert.True(t, keyTestEC.entity != keyRing.entities[len(keyRing.entities)-1])
+
+	updatedKeyRing, err := keyRingTestPrivate.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	entity := updatedKeyRing.entities[0]
+	config := &packet.Config{Time: getKeyGenerationTimeGenerator()}
+	selfSignature := *entity.PrimaryIdentity().SelfSignature
+	selfSignature.IsPrimaryId = nil
+	if err = selfSignature.SignUserId("merged <merged@example.com>", entity.PrimaryKey, entity.PrivateKey, config); err != nil {
+		t.Fatal("Expected no error while signing identity, got:", err)
+	}
+	entity.Identities["merged <merged@example.com>"] = &openpgp.Identity{
+		Name:          "merged <merged@example.com>",
+		UserId:        packet.NewUserId("merged", "", "merged@example.com"),
+		SelfSignature: &selfSignature,
+		Signatures:    []*packet.Signature{&selfSignature},
+	}
+	// An identity with the self-signature of another identity is not merged
+	entity.Identities["forged <forged@example.com>"] = &openpgp.Identity{
+		Name:          "forged <forged@example.com>",
+		UserId:        packet.NewUserId("forged", "", "forged@example.com"),
+		SelfSignature: entity.PrimaryIdentity().SelfSignature,
+		Signatures:    []*packet.Signature{entity.PrimaryIdentity().SelfSignature},
+	}
+	if err = entity.AddEncryptionSubkey(config); err != nil {
+		t.Fatal("Expected no error while adding subkey, got:", err)
+	}
+
+	if err = keyRing.Merge(updatedKeyRing); err != nil {
+		t.Fatal("Expected no error while merging keyring, got:", err)
+	}
+	assert.Exactly(t, keyRingTestMultiple.CountEntities(), keyRing.CountEntities())
+	var merged *openpgp.Entity
+	for _, e := range keyRing.entities {
+		if e.PrimaryKey.KeyId == entity.PrimaryKey.KeyId {
+			merged = e
+		}
+	}
+	assert.NotNil(t, merged)
+	assert.Len(t, merged.Identities, len(keyRingTestPrivate.entities[0].Identities)+1)
+	assert.Contains(t, merged.Identities, "merged <merged@example.com>")
+	assert.NotContains(t, merged.Identities, "forged <forged@example.com>")
+	assert.Len(t, merged.Subkeys, len(keyRingTestPrivate.entities[0].Subkeys)+1)
+	assert.True(t, entity.Subkeys[len(entity.Subkeys)-1].PrivateKey != merged.Subkeys[len(merged.Subkeys)-1].PrivateKey)
+
+	// Public subkeys are not merged into private keys, which can still be serialized
+	delete(entity.Identities, "forged <forged@example.com>")
+	updatedPublicKeyRing, err := updatedKeyRing.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public keys, got:", err)
+	}
+	privateKeyRing, err := keyRingTestPrivate.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	if err = privateKeyRing.Merge(updatedPublicKeyRing); err != nil {
+		t.Fatal("Expected no error while merging keyring, got:", err)
+	}
+	assert.Len(t, privateKeyRing.entities[0].Subkeys, len(keyRingTestPrivate.entities[0].Subkeys))
+	assert.Contains(t, privateKeyRing.entities[0].Identities, "merged <merged@example.com>")
+	if _, err = privateKeyRing.GetKeys()[0].Serialize(); err != nil {
+		t.Fatal("Expected no error while serializing merged key, got:", err)
+	}
+
+	// Only the public part of private subkeys is merged into public keys
+	publicKeyRing, err = keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	if err = publicKeyRing.Merge(updatedKeyRing); err != nil {
+		t.Fatal("Expected no error while merging keyring, got:", err)
+	}
+	subkeys := publicKeyRing.entities[0].Subkeys
+	assert.Len(t, subkeys, len(keyRingTestPublic.entities[0].Subkeys)+1)
+	assert.Nil(t, subkeys[len(subkeys)-1].PrivateKey)
+	assert.False(t, publicKeyRing.GetKeys()[0].IsPrivate())
+}
+
+func TestKeyRingToPublic(t *testing.T) {
+	publicKeyRing, err := keyRingTestMultiple.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public keys, got:", err)
+	}
+
+	assert.Exactly(t, keyRingTestMultiple.GetKeyIDs(), publicKeyRing.GetKeyIDs())
+	for _, key := range publicKeyRing.GetKeys() {
+		assert.False(t, key.IsPrivate())
+		for _, subkey := range key.entity.Subkeys {
+			assert.Nil(t, subkey.PrivateKey)
+		}
+	}
+	assert.True(t, keyRingTestMultiple.IsFullyUnlocked())
+
+	armored, err := publicKeyRing.GetArmoredPublicKeys()
+	if err != nil {
+		t.Fatal("Expected no error while armoring public keys, got:", err)
+	}
+	assert.NotContains(t, armored, "PRIVATE KEY")
+
+	ciphertext, err := publicKeyRing.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRingTestMultiple.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "plain text", decrypted.GetString())
+}
+
 func TestClearPrivateKey(t *testing.T) {
 	keyRingCopy, err := keyRingTestMultiple.Copy()
 	if err != nil {
@@ -200,6 +1064,41 @@ func TestClearPrivateParams(t *testing.T) {
 	}
 }
 
+func TestKeyRingLock(t *testing.T) {
+	keyRingCopy, err := keyRingTestMultiple.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	keyRingCopy.appendKey(keyRingTestPublic.GetKeys()[0])
+
+	lockedKeys, err := keyRingCopy.Lock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while locking keyring, got:", err)
+	}
+	assert.Len(t, lockedKeys, 4)
+	for _, key := range keyRingCopy.GetKeys() {
+		assert.False(t, key.IsPrivate())
+	}
+
+	for i, key := range lockedKeys[:3] {
+		locked, err := key.IsLocked()
+		if err != nil {
+			t.Fatal("Expected no error while checking if key is locked, got:", err)
+		}
+		assert.True(t, locked)
+
+		unlockedKey, err := key.Unlock(testMailboxPassword)
+		if err != nil {
+			t.Fatal("Expected no error while unlocking key, got:", err)
+		}
+		assert.Exactly(t, keyRingTestMultiple.GetKeys()[i].GetFingerprint(), unlockedKey.GetFingerprint())
+	}
+	assert.False(t, lockedKeys[3].IsPrivate())
+
+	// The keyring that was copied is not cleared
+	assert.True(t, keyRingTestMultiple.IsFullyUnlocked())
+}
+
 func TestEncryptedDetachedSignature(t *testing.T) {
 	keyRingPrivate, err := keyRingTestPrivate.Copy()
 	if err != nil {
@@ -225,6 +1124,93 @@ func TestEncryptedDetachedSignature(t *testing.T) {
 	}
 }
 
+func TestEncryptAndDetachSign(t *testing.T) {
+	message := NewPlainMessageFromString("Hello World!")
+	ciphertext, signature, err := keyRingTestPublic.EncryptAndDetachSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting and signing, got:", err)
+	}
+	_, ok := ciphertext.GetSignatureKeyIDs()
+	assert.False(t, ok)
+
+	err = keyRingTestPublic.VerifyDetached(NewPlainMessage(ciphertext.GetBinary()), signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying signature over ciphertext, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.VerifyDetachedAndDecrypt(ciphertext, signature, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying and decrypting, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	otherCiphertext, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, err = keyRingTestPrivate.VerifyDetachedAndDecrypt(otherCiphertext, signature, keyRingTestPublic, GetUnixTime())
+	assert.IsType(t, SignatureVerificationError{}, err)
+}
+
+func TestRevocation(t *testing.T) {
+	revocation, err := keyRingTestPrivate.GetKeys()[0].GenerateRevocation("key compromised")
+	if err != nil {
+		t.Fatal("Expected no error while generating revocation, got:", err)
+	}
+	assert.Contains(t, revocation, "-----BEGIN PGP PUBLIC KEY BLOCK-----")
+	assert.False(t, keyRingTestPrivate.GetKeys()[0].IsRevoked())
+
+	message := NewPlainMessageFromString("plain text")
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+
+	keyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	if err = keyRing.ApplyRevocation(revocation); err != nil {
+		t.Fatal("Expected no error while applying revocation, got:", err)
+	}
+	assert.True(t, keyRing.GetKeys()[0].IsRevoked())
+	assert.False(t, keyRing.GetKeys()[0].CanEncrypt())
+
+	_, err = keyRing.Encrypt(message, nil)
+	assert.Exactly(t, ErrKeyRevoked, err)
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	_, err = keyRing.EncryptSessionKey(sessionKey)
+	assert.Exactly(t, ErrKeyRevoked, err)
+	assert.NotNil(t, keyRing.VerifyDetached(message, signature, GetUnixTime()))
+
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.NotNil(t, ecKeyRing.ApplyRevocation(revocation))
+	ecRevocation, err := keyTestEC.GenerateRevocation("superseded")
+	if err != nil {
+		t.Fatal("Expected no error while generating revocation, got:", err)
+	}
+	ecPublicKeyRing, err := ecKeyRing.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public keys, got:", err)
+	}
+	if err = ecPublicKeyRing.ApplyRevocation(ecRevocation); err != nil {
+		t.Fatal("Expected no error while applying revocation, got:", err)
+	}
+	assert.True(t, ecPublicKeyRing.GetKeys()[0].IsRevoked())
+
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+	_, err = lockedKey.GenerateRevocation("")
+	assert.NotNil(t, err)
+}
+
 func TestKeyringCapabilities(t *testing.T) {
 	assert.True(t, keyRingTestPrivate.CanVerify())
 	assert.True(t, keyRingTestPrivate.CanEncrypt())
@@ -232,6 +1218,36 @@ func TestKeyringCapabilities(t *testing.T) {
 	assert.True(t, keyRingTestPublic.CanEncrypt())
 	assert.True(t, keyRingTestMultiple.CanVerify())
 	assert.True(t, keyRingTestMultiple.CanEncrypt())
+
+	assert.True(t, keyRingTestPrivate.CanSign())
+	assert.True(t, keyRingTestMultiple.CanSign())
+	assert.False(t, keyRingTestPublic.CanSign())
+
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+	assert.False(t, (&KeyRing{entities: openpgp.EntityList{lockedKey.entity}}).CanSign())
+
+	certifyOnlyKey, err := GenerateCertifyOnlyKey([]*Identity{{Name: keyTestName, Email: keyTestDomain}}, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	certifyOnlyKeyRing, err := NewKeyRing(certifyOnlyKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.True(t, certifyOnlyKeyRing.CanSign())
+
+	var encryptionSubkeys []openpgp.Subkey
+	for _, subkey := range certifyOnlyKey.entity.Subkeys {
+		if !subkey.Sig.FlagSign {
+			encryptionSubkeys = append(encryptionSubkeys, subkey)
+		}
+	}
+	certifyOnlyKey.entity.Subkeys = encryptionSubkeys
+	assert.False(t, certifyOnlyKeyRing.CanSign())
+	assert.True(t, certifyOnlyKeyRing.CanEncrypt())
 }
 
 func TestVerificationTime(t *testing.T) {
@@ -1,17 +1,23 @@
 package crypto
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/base64"
 	goerrors "errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpArmor "github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/ProtonMail/gopenpgp/v2/armor"
 	"github.com/ProtonMail/gopenpgp/v2/constants"
@@ -107,11 +113,15 @@ func NewPGPMessage(data []byte) *PGPMessage {
 	}
 }
 
+// ErrNotArmoredMessage is returned by NewPGPMessageFromArmored when the input
+// does not contain a valid armored block.
+var ErrNotArmoredMessage = errors.New("gopenpgp: input is not an armored message")
+
 // NewPGPMessageFromArmored generates a new PGPMessage from an armored string ready for decryption.
 func NewPGPMessageFromArmored(armored string) (*PGPMessage, error) {
 	encryptedIO, err := internal.Unarmor(armored)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in unarmoring message")
+		return nil, fmt.Errorf("%w: %v", ErrNotArmoredMessage, errors.Cause(err))
 	}
 
 	message, err := ioutil.ReadAll(encryptedIO.Body)
@@ -182,6 +192,9 @@ func NewClearTextMessage(data []byte, signature []byte) *ClearTextMessage {
 // signature from a clearsigned message.
 func NewClearTextMessageFromArmored(signedMessage string) (*ClearTextMessage, error) {
 	modulusBlock, rest := clearsign.Decode([]byte(signedMessage))
+	if modulusBlock == nil {
+		return nil, errors.New("gopenpgp: unable to find cleartext message")
+	}
 	if len(rest) != 0 {
 		return nil, errors.New("gopenpgp: extra data after modulus")
 	}
@@ -298,6 +311,27 @@ func (msg *PGPMessage) GetHexSignatureKeyIDs() ([]string, bool) {
 	return getHexKeyIDs(msg.GetSignatureKeyIDs())
 }
 
+// StripSignature returns the content of an inline signed, unencrypted
+// PGPMessage as a PlainMessage, discarding the signatures without verifying them.
+func (msg *PGPMessage) StripSignature() (*PlainMessage, error) {
+	md, err := openpgp.ReadMessage(msg.NewReader(), openpgp.EntityList{}, nil, &packet.Config{Time: getTimeGenerator()})
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading message")
+	}
+
+	body, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading message body")
+	}
+
+	return &PlainMessage{
+		Data:     body,
+		TextType: !md.LiteralData.IsBinary,
+		Filename: md.LiteralData.FileName,
+		Time:     md.LiteralData.Time,
+	}, nil
+}
+
 // GetBinaryDataPacket returns the unarmored binary datapacket as a []byte.
 func (msg *PGPSplitMessage) GetBinaryDataPacket() []byte {
 	return msg.DataPacket
@@ -432,6 +466,12 @@ func (msg *PGPSignature) GetArmored() (string, error) {
 	return armor.ArmorWithType(msg.Data, constants.PGPSignatureHeader)
 }
 
+// GetArmoredWithCustomHeaders returns the armored signature as a string, with
+// the given headers. Empty parameters are omitted from the headers.
+func (msg *PGPSignature) GetArmoredWithCustomHeaders(comment, version string) (string, error) {
+	return armor.ArmorWithTypeAndCustomHeaders(msg.Data, constants.PGPSignatureHeader, version, comment)
+}
+
 // GetSignatureKeyIDs Returns the key IDs of the keys to which the (readable) signature packets are encrypted to.
 func (msg *PGPSignature) GetSignatureKeyIDs() ([]uint64, bool) {
 	return getSignatureKeyIDs(msg.Data)
@@ -442,6 +482,25 @@ func (msg *PGPSignature) GetHexSignatureKeyIDs() ([]string, bool) {
 	return getHexKeyIDs(msg.GetSignatureKeyIDs())
 }
 
+// GetCreationTime returns the creation time, as a unix timestamp, of the first
+// readable signature packet, and false if there is none.
+func (msg *PGPSignature) GetCreationTime() (int64, bool) {
+	packets := packet.NewReader(bytes.NewReader(msg.Data))
+	for {
+		p, err := packets.Next()
+		var unsupported pgpErrors.UnsupportedError
+		if goerrors.As(err, &unsupported) {
+			continue
+		}
+		if err != nil {
+			return 0, false
+		}
+		if sig, ok := p.(*packet.Signature); ok {
+			return sig.CreationTime.Unix(), true
+		}
+	}
+}
+
 // GetBinary returns the unarmored signed data as a []byte.
 func (msg *ClearTextMessage) GetBinary() []byte {
 	return msg.Data
@@ -482,6 +541,182 @@ func IsPGPMessage(data string) bool {
 	return re.MatchString(data)
 }
 
+// IsPGPSignedMessage checks if data if has armored cleartext signed message format.
+func IsPGPSignedMessage(data string) bool {
+	re := regexp.MustCompile("^-----BEGIN " + constants.PGPSignedMessageHeader + "-----(?s:.+)-----END " +
+		constants.PGPSignatureHeader + "-----")
+	return re.MatchString(data)
+}
+
+// isBinaryPGPMessage checks if data starts with a packet that can start an
+// encrypted message: a session key packet or an encrypted data packet.
+func isBinaryPGPMessage(data []byte) bool {
+	if len(data) == 0 || data[0]&0x80 == 0 {
+		return false
+	}
+	p, err := packet.Read(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	switch p.(type) {
+	case *packet.EncryptedKey, *packet.SymmetricKeyEncrypted,
+		*packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
+		return true
+	default:
+		return false
+	}
+}
+
+// CountRecipients returns the number of recipients of an armored or binary
+// message, i.e. the number of public key and password encrypted session key
+// packets. No key is needed, as the message is only parsed structurally.
+func CountRecipients(message Reader) (int, error) {
+	reader, err := unarmorIfArmored(message)
+	if err != nil {
+		return 0, errors.Wrap(err, "gopenpgp: unable to unarmor message")
+	}
+
+	packets := packet.NewReader(reader)
+	count := 0
+	for {
+		p, err := packets.Next()
+		if goerrors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "gopenpgp: error in reading message")
+		}
+
+		switch p.(type) {
+		case *packet.EncryptedKey, *packet.SymmetricKeyEncrypted:
+			count++
+		default:
+			return count, nil
+		}
+	}
+}
+
+// ErrNoMatchingAccount is returned by RouteMessage when the message is not
+// encrypted to any of the accounts.
+var ErrNoMatchingAccount = errors.New("gopenpgp: message is not encrypted to any account")
+
+// ErrHiddenRecipient is returned by RouteMessage when the message is not
+// encrypted to any of the accounts, but has hidden recipients which may
+// belong to one of them. Such messages can only be routed by trial decryption.
+var ErrHiddenRecipient = errors.New("gopenpgp: message has hidden recipients and cannot be routed")
+
+// RouteMessage returns the ID of the account an armored or binary message is
+// encrypted to, by matching the key IDs of its public key encrypted session
+// key packets against the keys of each account. No decryption is performed.
+// If several accounts match, the smallest account ID is returned.
+func RouteMessage(message Reader, accounts map[string]*KeyRing) (accountID string, err error) {
+	keyIDs, hasHiddenRecipient, err := getRecipientKeyIDs(message)
+	if err != nil {
+		return "", err
+	}
+
+	accountIDs := make([]string, 0, len(accounts))
+	for id := range accounts {
+		accountIDs = append(accountIDs, id)
+	}
+	sort.Strings(accountIDs)
+
+	for _, id := range accountIDs {
+		for _, keyID := range keyIDs {
+			if len(accounts[id].entities.KeysById(keyID)) > 0 {
+				return id, nil
+			}
+		}
+	}
+
+	if hasHiddenRecipient {
+		return "", ErrHiddenRecipient
+	}
+	return "", ErrNoMatchingAccount
+}
+
+// MatchRecipients returns the primary identities of the known keys an armored
+// message is encrypted to, e.g. to show who can read it, by matching the key
+// IDs of its public key encrypted session key packets like RouteMessage.
+// Each key is listed once, in the order of the packets. Recipients whose key
+// is unknown, or hidden, are not listed.
+func MatchRecipients(armoredMessage string, knownKeys []*KeyRing) ([]*Identity, error) {
+	keyIDs, _, err := getRecipientKeyIDs(strings.NewReader(armoredMessage))
+	if err != nil {
+		return nil, err
+	}
+
+	var identities []*Identity
+	// The same key may be parsed several times, match keys by fingerprint
+	matched := make(map[string]bool)
+	for _, keyID := range keyIDs {
+		for _, keyRing := range knownKeys {
+			for _, key := range keyRing.entities.KeysById(keyID) {
+				fingerprint := string(key.Entity.PrimaryKey.Fingerprint)
+				if matched[fingerprint] {
+					continue
+				}
+				matched[fingerprint] = true
+				identity := (&KeyRing{entities: openpgp.EntityList{key.Entity}}).GetPrimaryIdentity()
+				if identity != nil {
+					identities = append(identities, identity)
+				}
+			}
+		}
+	}
+	return identities, nil
+}
+
+// getRecipientKeyIDs returns the key IDs of the public key encrypted session
+// key packets of an armored or binary message, and whether some of them
+// have a hidden recipient.
+func getRecipientKeyIDs(message Reader) (keyIDs []uint64, hasHiddenRecipient bool, err error) {
+	reader, err := unarmorIfArmored(message)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "gopenpgp: unable to unarmor message")
+	}
+
+	packets := packet.NewReader(reader)
+	for {
+		p, err := packets.Next()
+		if goerrors.Is(err, io.EOF) {
+			return keyIDs, hasHiddenRecipient, nil
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(err, "gopenpgp: error in reading message")
+		}
+
+		switch p := p.(type) {
+		case *packet.EncryptedKey:
+			if p.KeyId == 0 {
+				hasHiddenRecipient = true
+			} else {
+				keyIDs = append(keyIDs, p.KeyId)
+			}
+		case *packet.SymmetricKeyEncrypted:
+		default:
+			return keyIDs, hasHiddenRecipient, nil
+		}
+	}
+}
+
+// unarmorIfArmored returns a reader on the binary content of r, which can be
+// either armored or binary.
+func unarmorIfArmored(r io.Reader) (io.Reader, error) {
+	bufReader := bufio.NewReader(r)
+
+	// Binary packets always have the most significant bit of their first byte set
+	if firstByte, err := bufReader.Peek(1); err == nil && firstByte[0]&0x80 == 0 {
+		block, err := pgpArmor.Decode(bufReader)
+		if err != nil {
+			return nil, err
+		}
+		return block.Body, nil
+	}
+
+	return bufReader, nil
+}
+
 func getSignatureKeyIDs(data []byte) ([]uint64, bool) {
 	packets := packet.NewReader(bytes.NewReader(data))
 	var err error
@@ -5,10 +5,15 @@ import (
 	"encoding/base64"
 	"errors"
 	"io"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -227,6 +232,39 @@ func TestSignedMessageDecryption(t *testing.T) {
 	assert.Exactly(t, readTestFile("message_plaintext", true), decrypted.GetString())
 }
 
+func TestStripSignature(t *testing.T) {
+	var signed bytes.Buffer
+	plaintextWriter, err := openpgp.Sign(
+		&signed,
+		keyRingTestPrivate.entities[0],
+		&openpgp.FileHints{FileName: "file.txt"},
+		&packet.Config{Time: getTimeGenerator()},
+	)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	if _, err = plaintextWriter.Write([]byte("signed content")); err != nil {
+		t.Fatal("Expected no error when writing plaintext, got:", err)
+	}
+	if err = plaintextWriter.Close(); err != nil {
+		t.Fatal("Expected no error when closing plaintext writer, got:", err)
+	}
+
+	stripped, err := NewPGPMessage(signed.Bytes()).StripSignature()
+	if err != nil {
+		t.Fatal("Expected no error when stripping signature, got:", err)
+	}
+	assert.Exactly(t, "signed content", stripped.GetString())
+	assert.Exactly(t, "file.txt", stripped.Filename)
+
+	pgpMessage, err := NewPGPMessageFromArmored(readTestFile("message_signed", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	_, err = pgpMessage.StripSignature()
+	assert.NotNil(t, err)
+}
+
 func TestSHA256SignedMessageDecryption(t *testing.T) {
 	pgpMessage, err := NewPGPMessageFromArmored(readTestFile("message_sha256_signed", false))
 	if err != nil {
@@ -292,6 +330,365 @@ func TestMultipleKeyMessageEncryption(t *testing.T) {
 	assert.Exactly(t, message.GetString(), decrypted.GetString())
 }
 
+func TestSortedRecipientsMessageEncryption(t *testing.T) {
+	var message = NewPlainMessageFromString("plain text")
+
+	reversedKeyRing, err := NewKeyRing(nil)
+	if err != nil {
+		t.Fatal("Expected no error while building empty keyring, got:", err)
+	}
+	for i := len(keyRingTestMultiple.entities) - 1; i >= 0; i-- {
+		if err = reversedKeyRing.AddKey(&Key{keyRingTestMultiple.entities[i]}); err != nil {
+			t.Fatal("Expected no error while adding key to keyring, got:", err)
+		}
+	}
+
+	ciphertext, err := keyRingTestMultiple.EncryptWithSortedRecipients(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	reversedCiphertext, err := reversedKeyRing.EncryptWithSortedRecipients(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	ids, ok := ciphertext.GetEncryptionKeyIDs()
+	assert.True(t, ok)
+	assert.Exactly(t, 3, len(ids))
+	reversedIDs, ok := reversedCiphertext.GetEncryptionKeyIDs()
+	assert.True(t, ok)
+	assert.Exactly(t, ids, reversedIDs)
+	assert.True(t, sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i] < ids[j] }))
+
+	decrypted, err := keyRingTestPrivate.Decrypt(reversedCiphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+}
+
+func TestDecryptAllowExpired(t *testing.T) {
+	var message = NewPlainMessageFromString("plain text")
+
+	var ciphertext bytes.Buffer
+	// Signature created after the verification time, beyond the creation time offset
+	signatureTime := time.Unix(GetUnixTime()+3*24*60*60, 0)
+	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: func() time.Time { return signatureTime }}
+	plaintextWriter, err := openpgp.Encrypt(&ciphertext, keyRingTestPublic.entities, keyRingTestPrivate.entities[0], nil, config)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	if _, err = plaintextWriter.Write(message.GetBinary()); err != nil {
+		t.Fatal("Expected no error when writing plaintext, got:", err)
+	}
+	if err = plaintextWriter.Close(); err != nil {
+		t.Fatal("Expected no error when closing plaintext writer, got:", err)
+	}
+	encrypted := NewPGPMessage(ciphertext.Bytes())
+
+	_, err = keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	assert.NotNil(t, err)
+
+	decrypted, signature, expired, err := keyRingTestPrivate.DecryptAllowExpired(encrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+	assert.True(t, expired)
+	assert.NotNil(t, signature)
+	signatureKeyIDs, ok := signature.GetSignatureKeyIDs()
+	assert.True(t, ok)
+	assert.Exactly(t, keyRingTestPublic.GetKeyIDs(), signatureKeyIDs)
+
+	_, _, expired, err = keyRingTestPrivate.DecryptAllowExpired(encrypted, keyRingTestPublic, signatureTime.Unix())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.False(t, expired)
+}
+
+func TestMessageEncryptionWithCipher(t *testing.T) {
+	var message = NewPlainMessageFromString("plain text")
+
+	for _, algo := range []string{constants.AES128, constants.AES256, constants.CAST5} {
+		ciphertext, err := keyRingTestPublic.EncryptWithCipher(message, nil, algo)
+		if err != nil {
+			t.Fatal("Expected no error when encrypting, got:", err)
+		}
+
+		split, err := ciphertext.SeparateKeyAndData(1024, 0)
+		if err != nil {
+			t.Fatal("Expected no error when splitting, got:", err)
+		}
+		sessionKey, err := keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket())
+		if err != nil {
+			t.Fatal("Expected no error when decrypting session key, got:", err)
+		}
+		assert.Exactly(t, algo, sessionKey.Algo)
+
+		decrypted, err := keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, message.GetString(), decrypted.GetString())
+	}
+
+	_, err := keyRingTestPublic.EncryptWithCipher(message, nil, constants.AES192)
+	assert.NotNil(t, err)
+	_, err = keyRingTestPublic.EncryptWithCipher(message, nil, "rot13")
+	assert.NotNil(t, err)
+}
+
+func TestDecryptSecure(t *testing.T) {
+	var message = NewPlainMessageFromString("plain text")
+
+	signed, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.DecryptSecure(signed, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	_, err = keyRingTestPrivate.DecryptSecure(signed, nil, GetUnixTime())
+	assert.NotNil(t, err)
+
+	unsigned, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	_, err = keyRingTestPrivate.DecryptSecure(unsigned, keyRingTestPublic, GetUnixTime())
+	signatureErr := &SignatureVerificationError{}
+	assert.True(t, errors.As(err, signatureErr))
+	assert.Exactly(t, constants.SIGNATURE_NOT_SIGNED, signatureErr.Status)
+
+	tampered := clone(signed.GetBinary())
+	tampered[len(tampered)-1] ^= 1
+	_, err = keyRingTestPrivate.DecryptSecure(NewPGPMessage(tampered), keyRingTestPublic, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrIntegrityCheckFailed))
+
+	var signedOnly bytes.Buffer
+	plaintextWriter, err := openpgp.Sign(&signedOnly, keyRingTestPrivate.entities[0], nil, &packet.Config{Time: getTimeGenerator()})
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	_, _ = plaintextWriter.Write(message.GetBinary())
+	_ = plaintextWriter.Close()
+	_, err = keyRingTestPrivate.DecryptSecure(NewPGPMessage(signedOnly.Bytes()), keyRingTestPublic, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrMessageNotEncrypted))
+}
+
+func TestCountRecipients(t *testing.T) {
+	var message = NewPlainMessageFromString("plain text")
+
+	ciphertext, err := keyRingTestMultiple.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	count, err := CountRecipients(bytes.NewReader(ciphertext.GetBinary()))
+	if err != nil {
+		t.Fatal("Expected no error when counting recipients, got:", err)
+	}
+	assert.Exactly(t, 3, count)
+
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	count, err = CountRecipients(strings.NewReader(armored))
+	if err != nil {
+		t.Fatal("Expected no error when counting recipients, got:", err)
+	}
+	assert.Exactly(t, 3, count)
+
+	count, err = CountRecipients(strings.NewReader(readTestFile("message_mixedPasswordPublic", false)))
+	if err != nil {
+		t.Fatal("Expected no error when counting recipients, got:", err)
+	}
+	assert.Exactly(t, 2, count)
+
+	_, err = CountRecipients(strings.NewReader("not a message"))
+	assert.NotNil(t, err)
+}
+
+func TestMatchRecipients(t *testing.T) {
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	recipients, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	recipients.appendKey(keyRingTestPublic.GetKeys()[0])
+
+	ciphertext, err := recipients.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+
+	identities, err := MatchRecipients(armored, []*KeyRing{keyRingTestPublic, ecKeyRing, recipients})
+	if err != nil {
+		t.Fatal("Expected no error when matching recipients, got:", err)
+	}
+	assert.Exactly(t, []*Identity{ecKeyRing.GetPrimaryIdentity(), keyRingTestPublic.GetPrimaryIdentity()}, identities)
+
+	identities, err = MatchRecipients(armored, []*KeyRing{ecKeyRing})
+	if err != nil {
+		t.Fatal("Expected no error when matching recipients, got:", err)
+	}
+	assert.Exactly(t, []*Identity{ecKeyRing.GetPrimaryIdentity()}, identities)
+
+	// Separately parsed copies of the same key are listed once
+	ecKeyRingCopy, err := ecKeyRing.Copy()
+	if err != nil {
+		t.Fatal("Expected no error when copying keyring, got:", err)
+	}
+	identities, err = MatchRecipients(armored, []*KeyRing{ecKeyRing, ecKeyRingCopy})
+	if err != nil {
+		t.Fatal("Expected no error when matching recipients, got:", err)
+	}
+	assert.Exactly(t, []*Identity{ecKeyRing.GetPrimaryIdentity()}, identities)
+
+	_, err = MatchRecipients("not a message", nil)
+	assert.NotNil(t, err)
+}
+
+func TestRouteMessage(t *testing.T) {
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	accounts := map[string]*KeyRing{
+		"rsa": keyRingTestPublic,
+		"ec":  ecKeyRing,
+	}
+
+	ciphertext, err := ecKeyRing.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	accountID, err := RouteMessage(strings.NewReader(armored), accounts)
+	if err != nil {
+		t.Fatal("Expected no error when routing message, got:", err)
+	}
+	assert.Exactly(t, "ec", accountID)
+
+	ciphertext, err = keyRingTestPublic.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	accountID, err = RouteMessage(bytes.NewReader(ciphertext.GetBinary()), accounts)
+	if err != nil {
+		t.Fatal("Expected no error when routing message, got:", err)
+	}
+	assert.Exactly(t, "rsa", accountID)
+
+	_, err = RouteMessage(bytes.NewReader(ciphertext.GetBinary()), map[string]*KeyRing{"ec": ecKeyRing})
+	assert.Exactly(t, ErrNoMatchingAccount, err)
+
+	// Replace the recipient key ID of the session key packet with the anonymous key ID
+	hidden := ciphertext.GetBinary()
+	headerLength := 2
+	if hidden[1] >= 192 {
+		headerLength = 3
+	}
+	copy(hidden[headerLength+1:headerLength+9], make([]byte, 8))
+	_, err = RouteMessage(bytes.NewReader(hidden), accounts)
+	assert.Exactly(t, ErrHiddenRecipient, err)
+}
+
+func TestDecryptWithKeyID(t *testing.T) {
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+
+	for _, publicKeyRing := range []*KeyRing{keyRingTestPublic, ecKeyRing} {
+		ciphertext, err := publicKeyRing.Encrypt(NewPlainMessageFromString("plain text"), nil)
+		if err != nil {
+			t.Fatal("Expected no error when encrypting, got:", err)
+		}
+		encryptionKeyIDs, ok := ciphertext.GetEncryptionKeyIDs()
+		assert.True(t, ok)
+
+		decrypted, keyID, err := keyRingTestMultiple.DecryptWithKeyID(ciphertext, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, "plain text", decrypted.GetString())
+		assert.Exactly(t, encryptionKeyIDs[0], keyID)
+		assert.Exactly(t, publicKeyRing.entities[0].Subkeys[0].PublicKey.KeyId, keyID)
+	}
+}
+
+func TestCanDecrypt(t *testing.T) {
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+
+	canDecrypt, err := keyRingTestPrivate.CanDecrypt(strings.NewReader(armored))
+	if err != nil {
+		t.Fatal("Expected no error when checking message, got:", err)
+	}
+	assert.True(t, canDecrypt)
+
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+	lockedKeyRing := &KeyRing{entities: openpgp.EntityList{lockedKey.entity}}
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	for _, keyRing := range []*KeyRing{keyRingTestPublic, lockedKeyRing, ecKeyRing} {
+		canDecrypt, err = keyRing.CanDecrypt(bytes.NewReader(ciphertext.GetBinary()))
+		if err != nil {
+			t.Fatal("Expected no error when checking message, got:", err)
+		}
+		assert.False(t, canDecrypt)
+	}
+
+	// Replace the recipient key ID of the session key packet with the anonymous key ID
+	hidden := ciphertext.GetBinary()
+	headerLength := 2
+	if hidden[1] >= 192 {
+		headerLength = 3
+	}
+	copy(hidden[headerLength+1:headerLength+9], make([]byte, 8))
+	canDecrypt, err = ecKeyRing.CanDecrypt(bytes.NewReader(hidden))
+	if err != nil {
+		t.Fatal("Expected no error when checking message, got:", err)
+	}
+	assert.True(t, canDecrypt)
+	canDecrypt, err = keyRingTestPublic.CanDecrypt(bytes.NewReader(hidden))
+	if err != nil {
+		t.Fatal("Expected no error when checking message, got:", err)
+	}
+	assert.False(t, canDecrypt)
+
+	_, err = keyRingTestPrivate.CanDecrypt(strings.NewReader("-----BEGIN PGP MESSAGE-----\ninvalid"))
+	assert.NotNil(t, err)
+}
+
 func TestMessageGetEncryptionKeyIDs(t *testing.T) {
 	var message = NewPlainMessageFromString("plain text")
 	assert.Exactly(t, 3, len(keyRingTestMultiple.entities))
@@ -338,6 +735,22 @@ func TestMessageGetSignatureKeyIDs(t *testing.T) {
 	assert.Exactly(t, signingKey.PublicKey.KeyId, ids[0])
 }
 
+func TestSignatureGetCreationTime(t *testing.T) {
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString("plain text"))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+
+	creationTime, ok := signature.GetCreationTime()
+	assert.True(t, ok)
+	assert.Exactly(t, getTimeGenerator()().Unix(), creationTime)
+
+	_, ok = NewPGPSignature([]byte{}).GetCreationTime()
+	assert.False(t, ok)
+	_, ok = NewPGPSignature([]byte("not a signature")).GetCreationTime()
+	assert.False(t, ok)
+}
+
 func TestMessageGetHexSignatureKeyIDs(t *testing.T) {
 	ciphertext, err := NewPGPMessageFromArmored(readTestFile("message_plainSignature", false))
 	if err != nil {
@@ -387,3 +800,167 @@ func TestMessageGetArmoredWithEmptyHeaders(t *testing.T) {
 	assert.NotContains(t, armored, "Version")
 	assert.NotContains(t, armored, "Comment")
 }
+
+func TestMessageGetArmoredWithTrailingNewline(t *testing.T) {
+	var message = NewPlainMessageFromString("plain text")
+
+	ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Could not armor the ciphertext:", err)
+	}
+	assert.True(t, strings.HasSuffix(armored, "-----END PGP MESSAGE-----"))
+
+	armor.SetTrailingNewline(true)
+	defer armor.SetTrailingNewline(false)
+
+	armored, err = ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Could not armor the ciphertext:", err)
+	}
+	assert.True(t, strings.HasSuffix(armored, "-----END PGP MESSAGE-----\n"))
+
+	var buf bytes.Buffer
+	armorWriter, err := armor.ArmorWithTypeBuffered(&buf, constants.PGPMessageHeader)
+	if err != nil {
+		t.Fatal("Expected no error when creating armor writer, got:", err)
+	}
+	if _, err = armorWriter.Write(ciphertext.GetBinary()); err != nil {
+		t.Fatal("Expected no error when writing armored data, got:", err)
+	}
+	if err = armorWriter.Close(); err != nil {
+		t.Fatal("Expected no error when closing armor writer, got:", err)
+	}
+	assert.True(t, strings.HasSuffix(buf.String(), "-----END PGP MESSAGE-----\n"))
+}
+
+func TestExpectArmorType(t *testing.T) {
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Could not armor the ciphertext:", err)
+	}
+
+	assert.Nil(t, armor.ExpectArmorType("leading text\n"+armored, constants.PGPMessageHeader))
+	assert.Exactly(t, armor.ErrUnexpectedArmorType, armor.ExpectArmorType(armored, constants.PublicKeyHeader))
+
+	publicKey := readTestFile("keyring_publicKey", false)
+	assert.Nil(t, armor.ExpectArmorType(publicKey, constants.PublicKeyHeader))
+	assert.Exactly(t, armor.ErrUnexpectedArmorType, armor.ExpectArmorType(publicKey, constants.PGPMessageHeader))
+
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString("signed text"))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	signed, err := NewClearTextMessage([]byte("signed text"), signature.GetBinary()).GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring cleartext message, got:", err)
+	}
+	assert.Nil(t, armor.ExpectArmorType(signed, constants.PGPSignedMessageHeader))
+
+	assert.NotNil(t, armor.ExpectArmorType("not armored", constants.PGPMessageHeader))
+}
+
+func TestDecryptBytesIfNeeded(t *testing.T) {
+	message := NewPlainMessageFromString("plain text")
+	ciphertext, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(ciphertext.GetBinary())
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+
+	for _, data := range [][]byte{
+		ciphertext.GetBinary(),
+		[]byte(encoded),
+		[]byte(encoded[:40] + "\r\n" + encoded[40:]),
+		[]byte(armored),
+		[]byte("\n" + armored),
+	} {
+		plaintext, decrypted, err := keyRingTestPrivate.DecryptBytesIfNeeded(data)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.True(t, decrypted)
+		assert.Exactly(t, message.GetBinary(), plaintext)
+	}
+
+	for _, data := range [][]byte{
+		nil, []byte("plain text"), []byte("aGVsbG8="), {0xff, 0x00, 0x01},
+		[]byte("-----BEGIN PGP MESSAGE-----\n\nnot armored"),
+	} {
+		plaintext, decrypted, err := keyRingTestPrivate.DecryptBytesIfNeeded(data)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting plain data, got:", err)
+		}
+		assert.False(t, decrypted)
+		assert.Exactly(t, data, plaintext)
+	}
+
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	_, decrypted, err := ecKeyRing.DecryptBytesIfNeeded(ciphertext.GetBinary())
+	assert.NotNil(t, err)
+	assert.False(t, decrypted)
+}
+
+func TestNewPGPMessageFromArmoredErrors(t *testing.T) {
+	_, err := NewPGPMessageFromArmored("not an armored message")
+	assert.True(t, errors.Is(err, ErrNotArmoredMessage))
+	assert.Exactly(t, 1, strings.Count(err.Error(), "gopenpgp:"))
+}
+
+func TestIdentifyArmored(t *testing.T) {
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	armoredMessage, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Could not armor the ciphertext:", err)
+	}
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString("signed text"))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	armoredSignature, err := signature.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring signature, got:", err)
+	}
+	signed, err := NewClearTextMessage([]byte("signed text"), signature.GetBinary()).GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring cleartext message, got:", err)
+	}
+
+	kinds := map[string]string{
+		armoredMessage:                           constants.ArmoredMessage,
+		signed:                                   constants.ArmoredSignedMessage,
+		readTestFile("keyring_publicKey", false): constants.ArmoredPublicKey,
+		readTestFile("keyring_privateKey", false): constants.ArmoredPrivateKey,
+		armoredSignature: constants.ArmoredSignature,
+	}
+	for armored, expected := range kinds {
+		kind, err := armor.IdentifyArmored(armored)
+		if err != nil {
+			t.Fatal("Expected no error when identifying armored data, got:", err)
+		}
+		assert.Exactly(t, expected, kind)
+	}
+
+	_, err = armor.IdentifyArmored("-----BEGIN PGP ARMORED FILE-----\n")
+	assert.NotNil(t, err)
+	_, err = armor.IdentifyArmored("not armored")
+	assert.NotNil(t, err)
+}
@@ -292,9 +292,6 @@ func (sk *SessionKey) DecryptAndVerify(dataPacket []byte, verifyKeyRing *KeyRing
 }
 
 func decryptStreamWithSessionKey(sk *SessionKey, messageReader io.Reader, verifyKeyRing *KeyRing) (*openpgp.MessageDetails, error) {
-	var decrypted io.ReadCloser
-	var keyring openpgp.EntityList
-
 	// Read symmetrically encrypted data packet
 	packets := packet.NewReader(messageReader)
 	p, err := packets.Next()
@@ -302,6 +299,15 @@ func decryptStreamWithSessionKey(sk *SessionKey, messageReader io.Reader, verify
 		return nil, errors.Wrap(err, "gopenpgp: unable to read symmetric packet")
 	}
 
+	return decryptDataPacketWithSessionKey(sk, p, verifyKeyRing)
+}
+
+// decryptDataPacketWithSessionKey decrypts a data packet that has already
+// been read from the message with the session key.
+func decryptDataPacketWithSessionKey(sk *SessionKey, p packet.Packet, verifyKeyRing *KeyRing) (*openpgp.MessageDetails, error) {
+	var decrypted io.ReadCloser
+	var keyring openpgp.EntityList
+
 	// Decrypt data packet
 	switch p := p.(type) {
 	case *packet.SymmetricallyEncrypted:
@@ -315,6 +321,17 @@ func decryptStreamWithSessionKey(sk *SessionKey, messageReader io.Reader, verify
 			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt symmetric packet")
 		}
 
+	case *packet.AEADEncrypted:
+		dc, err := sk.GetCipherFunc()
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt with session key")
+		}
+
+		decrypted, err = p.Decrypt(dc, sk.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt symmetric packet")
+		}
+
 	default:
 		return nil, errors.New("gopenpgp: invalid packet type")
 	}
@@ -1,10 +1,12 @@
 package crypto
 
 import (
+	"bytes"
 	"encoding/base64"
 	"errors"
 	"testing"
 
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/ProtonMail/gopenpgp/v2/constants"
 	"github.com/stretchr/testify/assert"
 )
@@ -271,6 +273,39 @@ func TestDataPacketDecryption(t *testing.T) {
 	assert.Exactly(t, readTestFile("message_plaintext", true), decrypted.GetString())
 }
 
+func TestAEADDataPacketDecryption(t *testing.T) {
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	cipherFunc, err := sessionKey.GetCipherFunc()
+	if err != nil {
+		t.Fatal("Expected no error while getting cipher function, got:", err)
+	}
+
+	var dataPacket bytes.Buffer
+	encrypted, err := packet.SerializeAEADEncrypted(&dataPacket, sessionKey.Key, cipherFunc, packet.AEADModeEAX, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting AEAD packet, got:", err)
+	}
+	literal, err := packet.SerializeLiteral(encrypted, true, "", 0)
+	if err != nil {
+		t.Fatal("Expected no error while writing literal packet, got:", err)
+	}
+	if _, err = literal.Write([]byte(signedPlainText)); err != nil {
+		t.Fatal("Expected no error while writing message, got:", err)
+	}
+	if err = literal.Close(); err != nil {
+		t.Fatal("Expected no error while closing literal packet, got:", err)
+	}
+
+	decrypted, err := sessionKey.Decrypt(dataPacket.Bytes())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, signedPlainText, decrypted.GetString())
+}
+
 func TestSessionKeyClear(t *testing.T) {
 	testSessionKey.Clear()
 	assertMemCleared(t, testSessionKey.Key)
@@ -36,6 +36,36 @@ func (e SignatureVerificationError) Error() string {
 	return fmt.Sprintf("Signature Verification Error: %v", e.Message)
 }
 
+// VerifyDetachedWithBareKey verifies a PlainMessage with a detached PGPSignature
+// using a bare public key packet, without user ID nor self-signature,
+// and returns a SignatureVerificationError if fails.
+// A bare key carries no self-signature, hence its expiration, revocation
+// status and usage flags cannot be checked: a successful verification only
+// proves that the signature was made with the given key material. The caller
+// must establish trust in the key by other means, e.g. its fingerprint.
+func VerifyDetachedWithBareKey(message *PlainMessage, signature *PGPSignature, rawPublicKey []byte, verifyTime int64) error {
+	p, err := packet.Read(bytes.NewReader(rawPublicKey))
+	if err != nil {
+		return fmt.Errorf("gopenpgp: error in reading bare public key: %w", err)
+	}
+
+	publicKey, ok := p.(*packet.PublicKey)
+	if !ok {
+		return errors.New("gopenpgp: the bare key is not a public key packet")
+	}
+
+	// The placeholder identity is never verified, it only allows the entity
+	// to be used as a signer, with neither expiration nor usage restrictions.
+	entity := &openpgp.Entity{
+		PrimaryKey: publicKey,
+		Identities: map[string]*openpgp.Identity{
+			"": {SelfSignature: &packet.Signature{}},
+		},
+	}
+
+	return verifySignature(openpgp.EntityList{entity}, message.NewReader(), signature.GetBinary(), verifyTime)
+}
+
 // ------------------
 // Internal functions
 // ------------------
@@ -97,6 +127,25 @@ func processSignatureExpiration(md *openpgp.MessageDetails, verifyTime int64) {
 	}
 }
 
+// keyStatusAt returns whether a key, or subkey, or its primary key is revoked,
+// and whether one of them is expired at the given time.
+func keyStatusAt(key openpgp.Key, at time.Time) (revoked, expired bool) {
+	e := key.Entity
+	revoked = len(e.Revocations) > 0
+	if identity := e.PrimaryIdentity(); identity != nil {
+		expired = e.PrimaryKey.KeyExpired(identity.SelfSignature, at)
+	}
+	if key.PublicKey == e.PrimaryKey || key.SelfSignature == nil {
+		return revoked, expired
+	}
+
+	// The binding signature of a revoked subkey is its revocation signature
+	if key.SelfSignature.SigType == packet.SigTypeSubkeyRevocation || key.SelfSignature.RevocationReason != nil {
+		return true, expired
+	}
+	return revoked, expired || key.PublicKey.KeyExpired(key.SelfSignature, at)
+}
+
 // verifyDetailsSignature verifies signature from message details.
 func verifyDetailsSignature(md *openpgp.MessageDetails, verifierKey *KeyRing) error {
 	if !md.IsSigned {
@@ -146,6 +195,12 @@ func verifySignature(pubKeyEntries openpgp.EntityList, origText io.Reader, signa
 		if err != nil {
 			return newSignatureFailed()
 		}
+		// The message has been read by the first check, rewind it if possible
+		if seeker, ok := origText.(io.Seeker); ok {
+			if _, err = seeker.Seek(0, io.SeekStart); err != nil {
+				return newSignatureFailed()
+			}
+		}
 
 		signer, err = openpgp.CheckDetachedSignatureAndHash(pubKeyEntries, origText, signatureReader, allowedHashes, config)
 		if err != nil {
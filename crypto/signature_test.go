@@ -1,11 +1,21 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
 	"errors"
 	"regexp"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,3 +83,455 @@ func TestVerifyBinDetachedSig(t *testing.T) {
 		t.Fatal("Cannot verify binary signature:", verificationError)
 	}
 }
+
+func TestVerifyDetachedMinKeySize(t *testing.T) {
+	err := keyRingTestPublic.VerifyDetachedMinKeySize(message, binSignature, testTime, 2048)
+	if err != nil {
+		t.Fatal("Expected no error when verifying signature, got:", err)
+	}
+
+	err = keyRingTestPublic.VerifyDetachedMinKeySize(message, binSignature, testTime, 4096)
+	assert.Exactly(t, ErrWeakKey, err)
+
+	fakeMessage := NewPlainMessageFromString("wrong text")
+	err = keyRingTestPublic.VerifyDetachedMinKeySize(fakeMessage, binSignature, testTime, 4096)
+	assert.IsType(t, SignatureVerificationError{}, err)
+
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	ecSignature, err := ecKeyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	err = ecKeyRing.VerifyDetachedMinKeySize(message, ecSignature, GetUnixTime(), 4096)
+	if err != nil {
+		t.Fatal("Expected no error when verifying EC signature, got:", err)
+	}
+}
+
+func TestSigningSubkeyID(t *testing.T) {
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA, Time: getKeyGenerationTimeGenerator()}
+	entity, err := openpgp.NewEntity(keyTestName, "", keyTestDomain, config)
+	if err != nil {
+		t.Fatal("Expected no error when generating key, got:", err)
+	}
+	if err = entity.AddSigningSubkey(config); err != nil {
+		t.Fatal("Expected no error when adding signing subkey, got:", err)
+	}
+
+	subkeyMessage := NewPlainMessageFromString(signedPlainText)
+	keyRing, err := NewKeyRing(&Key{entity})
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	signature, err := keyRing.SignDetached(subkeyMessage)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	if err = keyRing.VerifyDetached(subkeyMessage, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error when verifying signature, got:", err)
+	}
+
+	subkeyID, ok := keyRing.SigningSubkeyID(signature)
+	assert.True(t, ok)
+	assert.Exactly(t, entity.Subkeys[1].PublicKey.KeyId, subkeyID)
+
+	_, ok = keyRingTestPublic.SigningSubkeyID(signature)
+	assert.False(t, ok)
+
+	primarySignature, err := keyRingTestPrivate.SignDetached(subkeyMessage)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	_, ok = keyRingTestPublic.SigningSubkeyID(primarySignature)
+	assert.False(t, ok)
+}
+
+func TestVerifyDetachedWithGrace(t *testing.T) {
+	message := NewPlainMessageFromString(signedPlainText)
+	created := GetUnixTime()
+	config := &packet.Config{
+		DefaultHash:     crypto.SHA256,
+		SigLifetimeSecs: 3600,
+		Time: func() time.Time {
+			return time.Unix(created, 0)
+		},
+	}
+	var outBuf bytes.Buffer
+	if err := openpgp.DetachSign(&outBuf, keyRingTestPrivate.entities[0], message.NewReader(), config); err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	signature := NewPGPSignature(outBuf.Bytes())
+	expires := created + 3600
+
+	// The signature is expired at created+60 plus the creation time offset,
+	// so the message is verified a second time at created+60
+	assert.Nil(t, keyRingTestPublic.VerifyDetached(message, signature, created+60))
+	assert.Nil(t, keyRingTestPublic.VerifyDetachedStream(message.NewReader(), signature, created+60))
+	assert.Nil(t, keyRingTestPublic.VerifyDetachedWithGrace(message, signature, created+60, 0))
+	assert.NotNil(t, keyRingTestPublic.VerifyDetached(message, signature, expires+600))
+	assert.Nil(t, keyRingTestPublic.VerifyDetachedWithGrace(message, signature, expires+600, time.Hour))
+	assert.NotNil(t, keyRingTestPublic.VerifyDetachedWithGrace(message, signature, expires+600, 5*time.Minute))
+	assert.NotNil(t, keyRingTestPublic.VerifyDetachedWithGrace(message, signature, expires+7200, time.Hour))
+
+	tampered := NewPlainMessageFromString("Tampered message\n")
+	assert.NotNil(t, keyRingTestPublic.VerifyDetachedWithGrace(tampered, signature, expires+600, time.Hour))
+
+	expiredKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	lifetime := uint32(expires + 300 - keyRingTestPublic.entities[0].PrimaryKey.CreationTime.Unix())
+	for _, identity := range expiredKeyRing.entities[0].Identities {
+		identity.SelfSignature.KeyLifetimeSecs = &lifetime
+	}
+	assert.NotNil(t, expiredKeyRing.VerifyDetachedWithGrace(message, signature, expires+600, time.Hour))
+}
+
+func TestVerifyDetachedBatch(t *testing.T) {
+	items := make([]VerifyItem, 20)
+	for i := range items {
+		message := NewPlainMessageFromString(signedPlainText + strconv.Itoa(i))
+		signature, err := keyRingTestPrivate.SignDetached(message)
+		if err != nil {
+			t.Fatal("Cannot generate signature:", err)
+		}
+		items[i] = VerifyItem{Message: message, Signature: signature}
+	}
+	items[3].Message = NewPlainMessageFromString("wrong text")
+	items[11].Signature = items[12].Signature
+
+	results := keyRingTestPublic.VerifyDetachedBatch(items, GetUnixTime())
+	assert.Len(t, results, len(items))
+	for i, err := range results {
+		if i == 3 || i == 11 {
+			assert.IsType(t, SignatureVerificationError{}, err)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+
+	assert.Empty(t, keyRingTestPublic.VerifyDetachedBatch(nil, GetUnixTime()))
+}
+
+func TestSignDetachedWithKeyID(t *testing.T) {
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA, Time: getKeyGenerationTimeGenerator()}
+	entity, err := openpgp.NewEntity(keyTestName, "", keyTestDomain, config)
+	if err != nil {
+		t.Fatal("Expected no error when generating key, got:", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = entity.AddSigningSubkey(config); err != nil {
+			t.Fatal("Expected no error when adding signing subkey, got:", err)
+		}
+	}
+
+	keyRing, err := NewKeyRing(&Key{entity})
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	if err = keyRing.AddKey(keyTestEC); err != nil {
+		t.Fatal("Expected no error when adding key, got:", err)
+	}
+
+	message := NewPlainMessageFromString(signedPlainText)
+	for _, keyID := range []uint64{
+		entity.Subkeys[1].PublicKey.KeyId,
+		entity.Subkeys[2].PublicKey.KeyId,
+		keyTestEC.entity.PrimaryKey.KeyId,
+	} {
+		signature, err := keyRing.SignDetachedWithKeyID(message, keyID)
+		if err != nil {
+			t.Fatal("Expected no error when signing, got:", err)
+		}
+		signatureKeyIDs, ok := signature.GetSignatureKeyIDs()
+		assert.True(t, ok)
+		assert.Exactly(t, []uint64{keyID}, signatureKeyIDs)
+
+		if err = keyRing.VerifyDetached(message, signature, GetUnixTime()); err != nil {
+			t.Fatal("Expected no error when verifying signature, got:", err)
+		}
+	}
+
+	_, err = keyRing.SignDetachedWithKeyID(message, keyRingTestPublic.entities[0].PrimaryKey.KeyId)
+	assert.NotNil(t, err)
+
+	_, err = keyRingTestPublic.SignDetachedWithKeyID(message, keyRingTestPublic.entities[0].PrimaryKey.KeyId)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyDetachedStrictBinding(t *testing.T) {
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA, Time: getKeyGenerationTimeGenerator()}
+	entity, err := openpgp.NewEntity(keyTestName, "", keyTestDomain, config)
+	if err != nil {
+		t.Fatal("Expected no error when generating key, got:", err)
+	}
+	if err = entity.AddSigningSubkey(config); err != nil {
+		t.Fatal("Expected no error when adding signing subkey, got:", err)
+	}
+
+	subkeyMessage := NewPlainMessageFromString(signedPlainText)
+	keyRing, err := NewKeyRing(&Key{entity})
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	signature, err := keyRing.SignDetached(subkeyMessage)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	if err = keyRing.VerifyDetachedStrictBinding(subkeyMessage, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error when verifying signature, got:", err)
+	}
+
+	// Cross-signature made by another key
+	otherEntity, err := openpgp.NewEntity(keyTestName, "", keyTestDomain, config)
+	if err != nil {
+		t.Fatal("Expected no error when generating key, got:", err)
+	}
+	if err = otherEntity.AddSigningSubkey(config); err != nil {
+		t.Fatal("Expected no error when adding signing subkey, got:", err)
+	}
+	crossSignature := entity.Subkeys[1].Sig.EmbeddedSignature
+	entity.Subkeys[1].Sig.EmbeddedSignature = otherEntity.Subkeys[1].Sig.EmbeddedSignature
+	assert.Exactly(t, ErrInvalidSubkeyBinding, keyRing.VerifyDetachedStrictBinding(subkeyMessage, signature, GetUnixTime()))
+
+	entity.Subkeys[1].Sig.EmbeddedSignature = nil
+	assert.Exactly(t, ErrInvalidSubkeyBinding, keyRing.VerifyDetachedStrictBinding(subkeyMessage, signature, GetUnixTime()))
+
+	entity.Subkeys[1].Sig.EmbeddedSignature = crossSignature
+	if err = keyRing.VerifyDetachedStrictBinding(subkeyMessage, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error when verifying signature, got:", err)
+	}
+
+	primarySignature, err := keyRingTestPrivate.SignDetached(subkeyMessage)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	if err = keyRingTestPublic.VerifyDetachedStrictBinding(subkeyMessage, primarySignature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error when verifying signature, got:", err)
+	}
+}
+
+func TestSignaturesMatch(t *testing.T) {
+	content := []byte("migrated content")
+
+	var inline bytes.Buffer
+	config := &packet.Config{DefaultHash: crypto.SHA256, Time: getTimeGenerator()}
+	writer, err := openpgp.Sign(&inline, keyTestRSA.entity, &openpgp.FileHints{IsBinary: true}, config)
+	if err != nil {
+		t.Fatal("Expected no error when creating inline signer, got:", err)
+	}
+	if _, err = writer.Write(content); err != nil {
+		t.Fatal("Expected no error when signing inline, got:", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error when closing inline signer, got:", err)
+	}
+	inlineSigned := NewPGPMessage(inline.Bytes())
+
+	rsaKeyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+
+	detached, err := rsaKeyRing.SignDetached(NewPlainMessage(content))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	match, err := keyRingTestMultiple.SignaturesMatch(detached, inlineSigned, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when matching signatures, got:", err)
+	}
+	assert.True(t, match)
+
+	otherContent, err := rsaKeyRing.SignDetached(NewPlainMessageFromString("other content"))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	match, err = keyRingTestMultiple.SignaturesMatch(otherContent, inlineSigned, GetUnixTime())
+	assert.False(t, match)
+	assert.Exactly(t, ErrContentMismatch, err)
+
+	otherSigner, err := ecKeyRing.SignDetached(NewPlainMessage(content))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	match, err = keyRingTestMultiple.SignaturesMatch(otherSigner, inlineSigned, GetUnixTime())
+	assert.False(t, match)
+	assert.Exactly(t, ErrSignerMismatch, err)
+
+	_, err = ecKeyRing.SignaturesMatch(otherSigner, inlineSigned, GetUnixTime())
+	assert.IsType(t, SignatureVerificationError{}, err)
+
+	// The inline signature is verified at verifyTime
+	_, err = keyRingTestMultiple.SignaturesMatch(detached, inlineSigned, GetUnixTime()-10*internal.CreationTimeOffset)
+	assert.IsType(t, SignatureVerificationError{}, err)
+}
+
+func TestSignVerifyDetachedWithContext(t *testing.T) {
+	message := NewPlainMessageFromString("request body")
+
+	signature, err := keyRingTestPrivate.SignDetachedWithContext(message, "request-1")
+	if err != nil {
+		t.Fatal("Expected no error while signing with context, got:", err)
+	}
+
+	err = keyRingTestPublic.VerifyDetachedWithContext(message, signature, "request-1", GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying with context, got:", err)
+	}
+
+	err = keyRingTestPublic.VerifyDetachedStreamWithContext(message.NewReader(), signature, "request-1", GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying stream with context, got:", err)
+	}
+
+	err = keyRingTestPublic.VerifyDetachedWithContext(message, signature, "request-2", GetUnixTime())
+	assert.IsType(t, SignatureVerificationError{}, err)
+
+	err = keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime())
+	assert.IsType(t, SignatureVerificationError{}, err)
+
+	// The context prefix is length delimited, so moving bytes between the
+	// context and the message invalidates the signature.
+	shifted := NewPlainMessageFromString("1request body")
+	err = keyRingTestPublic.VerifyDetachedWithContext(shifted, signature, "request-", GetUnixTime())
+	assert.IsType(t, SignatureVerificationError{}, err)
+}
+
+func TestSignDetachedExternal(t *testing.T) {
+	externalMessage := NewPlainMessage([]byte(signedPlainText))
+
+	for _, key := range []*Key{keyTestRSA, keyTestEC} {
+		signingKey, ok := key.entity.SigningKey(getNow())
+		if !ok {
+			t.Fatal("Expected a signing key")
+		}
+
+		signRaw := func(digest []byte) (r, s []byte, err error) {
+			switch priv := signingKey.PrivateKey.PrivateKey.(type) {
+			case *rsa.PrivateKey:
+				r, err = rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest)
+				return r, nil, err
+			case *ed25519.PrivateKey:
+				sig := ed25519.Sign(*priv, digest)
+				return sig[:32], sig[32:], nil
+			}
+			return nil, nil, errors.New("unexpected private key type")
+		}
+
+		publicKey, err := key.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while extracting public key, got:", err)
+		}
+		publicKeyRing, err := NewKeyRing(publicKey)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+
+		signature, err := publicKeyRing.SignDetachedExternal(externalMessage.NewReader(), crypto.SHA256, signRaw)
+		if err != nil {
+			t.Fatal("Expected no error while signing externally, got:", err)
+		}
+
+		verificationError := publicKeyRing.VerifyDetached(externalMessage, signature, GetUnixTime())
+		if verificationError != nil {
+			t.Fatal("Cannot verify external signature:", verificationError)
+		}
+	}
+
+	errHSM := errors.New("hsm unavailable")
+	for _, key := range []*Key{keyTestRSA, keyTestEC} {
+		publicKey, err := key.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while extracting public key, got:", err)
+		}
+		publicKeyRing, err := NewKeyRing(publicKey)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		_, err = publicKeyRing.SignDetachedExternal(externalMessage.NewReader(), crypto.SHA256, func([]byte) ([]byte, []byte, error) {
+			return nil, nil, errHSM
+		})
+		assert.True(t, errors.Is(err, errHSM))
+	}
+}
+
+func TestSignDetachedExternalInvalidEdDSALength(t *testing.T) {
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	publicKeyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	message := NewPlainMessage([]byte(signedPlainText))
+	_, err = publicKeyRing.SignDetachedExternal(message.NewReader(), crypto.SHA256, func([]byte) ([]byte, []byte, error) {
+		return make([]byte, 33), make([]byte, 32), nil
+	})
+	assert.EqualError(t, err, "gopenpgp: invalid EdDSA signature length")
+}
+
+func TestSignDetachedExternalUnsupportedAlgorithm(t *testing.T) {
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	publicKeyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	signingKey, ok := publicKeyRing.entities[0].SigningKey(getNow())
+	if !ok {
+		t.Fatal("Expected a signing key")
+	}
+	signingKey.PublicKey.PubKeyAlgo = packet.PubKeyAlgoDSA
+
+	message := NewPlainMessage([]byte(signedPlainText))
+	_, err = publicKeyRing.SignDetachedExternal(message.NewReader(), crypto.SHA256, func([]byte) ([]byte, []byte, error) {
+		t.Fatal("Expected the external signer not to be called")
+		return nil, nil, nil
+	})
+	assert.EqualError(t, err, "gopenpgp: unsupported public key algorithm for external signing")
+}
+
+func TestVerifyDetachedWithBareKey(t *testing.T) {
+	signingKeyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	bareMessage := NewPlainMessage([]byte(signedPlainText))
+	signature, err := signingKeyRing.SignDetached(bareMessage)
+	if err != nil {
+		t.Fatal("Cannot generate signature:", err)
+	}
+
+	var rawPublicKey bytes.Buffer
+	if err = keyTestRSA.entity.PrimaryKey.Serialize(&rawPublicKey); err != nil {
+		t.Fatal("Cannot serialize bare public key:", err)
+	}
+
+	verificationError := VerifyDetachedWithBareKey(bareMessage, signature, rawPublicKey.Bytes(), GetUnixTime())
+	if verificationError != nil {
+		t.Fatal("Cannot verify signature with bare key:", verificationError)
+	}
+
+	verificationError = VerifyDetachedWithBareKey(NewPlainMessageFromString("wrong text"), signature, rawPublicKey.Bytes(), GetUnixTime())
+	assert.EqualError(t, verificationError, "Signature Verification Error: Invalid signature")
+
+	var otherPublicKey bytes.Buffer
+	if err = keyTestEC.entity.PrimaryKey.Serialize(&otherPublicKey); err != nil {
+		t.Fatal("Cannot serialize bare public key:", err)
+	}
+	verificationError = VerifyDetachedWithBareKey(bareMessage, signature, otherPublicKey.Bytes(), GetUnixTime())
+	assert.NotNil(t, verificationError)
+}
@@ -0,0 +1,172 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// SignatureStatus is the outcome of the verification of a signature.
+// It is serialized to JSON as a stable string, see String.
+type SignatureStatus int
+
+// Statuses of a SignatureVerification.
+const (
+	SignatureStatusValid SignatureStatus = iota
+	SignatureStatusExpired
+	SignatureStatusRevoked
+	SignatureStatusNoSignature
+	SignatureStatusNoVerifier
+	SignatureStatusBad
+)
+
+var signatureStatusNames = map[SignatureStatus]string{
+	SignatureStatusValid:       "valid",
+	SignatureStatusExpired:     "expired",
+	SignatureStatusRevoked:     "revoked",
+	SignatureStatusNoSignature: "no-signature",
+	SignatureStatusNoVerifier:  "no-verifier",
+	SignatureStatusBad:         "bad",
+}
+
+// String returns the name of the status: "valid", "expired", "revoked",
+// "no-signature", "no-verifier" or "bad".
+func (status SignatureStatus) String() string {
+	if name, ok := signatureStatusNames[status]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// MarshalJSON serializes the status as its name.
+func (status SignatureStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(status.String())
+}
+
+// SignatureVerification contains the result of the verification of a
+// signature, ready to be serialized to JSON.
+// The signer fields are only set if the signing key is in the keyring.
+type SignatureVerification struct {
+	Status SignatureStatus `json:"status"`
+	// Fingerprint is the hex fingerprint of the primary key of the signer.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// KeyID is the hex key ID of the key, or subkey, that made the signature.
+	KeyID        string    `json:"keyId,omitempty"`
+	Identity     *Identity `json:"identity,omitempty"`
+	CreationTime time.Time `json:"creationTime"`
+}
+
+// VerifyDetachedWithDetails verifies a PlainMessage with a detached
+// PGPSignature like VerifyDetached, and returns a SignatureVerification
+// describing the signature and its signer instead of a
+// SignatureVerificationError. An error is only returned if the verification
+// could not be performed.
+func (keyRing *KeyRing) VerifyDetachedWithDetails(
+	message *PlainMessage, signature *PGPSignature, verifyTime int64,
+) (*SignatureVerification, error) {
+	return keyRing.VerifyDetachedStreamWithDetails(message.NewReader(), signature, verifyTime)
+}
+
+// VerifyDetachedStreamWithDetails verifies a message reader with a detached
+// PGPSignature like VerifyDetachedStream, and returns a SignatureVerification
+// like VerifyDetachedWithDetails. The message is read only once.
+func (keyRing *KeyRing) VerifyDetachedStreamWithDetails(
+	message Reader, signature *PGPSignature, verifyTime int64,
+) (*SignatureVerification, error) {
+	verification := &SignatureVerification{Status: SignatureStatusNoSignature}
+
+	p, err := packet.Read(bytes.NewReader(signature.GetBinary()))
+	if err != nil {
+		return verification, nil
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return verification, nil
+	}
+	verification.CreationTime = sig.CreationTime
+
+	keys := keyRing.signatureIssuerKeys(sig)
+	if len(keys) == 0 {
+		verification.Status = SignatureStatusNoVerifier
+		return verification, nil
+	}
+
+	verification.Status = SignatureStatusBad
+	verification.setSigner(keys[0])
+	if sig.Hash < allowedHashes[0] || sig.Hash > allowedHashes[len(allowedHashes)-1] ||
+		(sig.SigType != packet.SigTypeBinary && sig.SigType != packet.SigTypeText) {
+		return verification, nil
+	}
+
+	hashed := sig.Hash.New()
+	wrappedHash := hashed
+	if sig.SigType == packet.SigTypeText {
+		wrappedHash = openpgp.NewCanonicalTextHash(hashed)
+	}
+	if _, err = io.Copy(wrappedHash, message); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading message")
+	}
+
+	for _, key := range keys {
+		// Each verification hashes the signature trailer, use a copy of the message hash
+		signed, err := cloneHash(sig.Hash, hashed)
+		if err != nil {
+			return nil, err
+		}
+		if key.PublicKey.VerifySignature(signed, sig) != nil {
+			continue
+		}
+
+		verification.setSigner(key)
+		switch checkSignatureTime(sig, key, verifyTime) {
+		case nil:
+			verification.Status = SignatureStatusValid
+		case pgpErrors.ErrKeyRevoked:
+			verification.Status = SignatureStatusRevoked
+		default:
+			verification.Status = SignatureStatusExpired
+		}
+		if verification.Status == SignatureStatusValid {
+			break
+		}
+	}
+	return verification, nil
+}
+
+// signatureIssuerKeys returns the keys, and subkeys, of the keyring that may
+// have made the signature, found by its issuer key ID and, if the signature
+// has one, its issuer fingerprint. Keys that cannot sign are skipped.
+// When parsing the signature, the issuer key ID is derived from the issuer
+// fingerprint if the signature only has the latter.
+func (keyRing *KeyRing) signatureIssuerKeys(sig *packet.Signature) (keys []openpgp.Key) {
+	if sig.IssuerKeyId == nil {
+		return nil
+	}
+
+	for _, key := range keyRing.entities.KeysById(*sig.IssuerKeyId) {
+		if sig.IssuerFingerprint != nil && !bytes.Equal(key.PublicKey.Fingerprint, sig.IssuerFingerprint) {
+			continue
+		}
+		if key.SelfSignature != nil && key.SelfSignature.FlagsValid && !key.SelfSignature.FlagSign {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// setSigner sets the signer fields of the verification to the given key.
+func (verification *SignatureVerification) setSigner(key openpgp.Key) {
+	verification.Fingerprint = (&Key{key.Entity}).GetFingerprint()
+	verification.KeyID = keyIDToHex(key.PublicKey.KeyId)
+	verification.Identity = nil
+	if identity := key.Entity.PrimaryIdentity(); identity != nil {
+		verification.Identity = &Identity{Name: identity.UserId.Name, Email: identity.UserId.Email}
+	}
+}
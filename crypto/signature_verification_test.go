@@ -0,0 +1,191 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyDetachedWithDetails(t *testing.T) {
+	signedMessage := NewPlainMessageFromString(signedPlainText)
+	signature, err := keyRingTestPrivate.SignDetached(signedMessage)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+
+	verification, err := keyRingTestPublic.VerifyDetachedWithDetails(signedMessage, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusValid, verification.Status)
+	assert.Exactly(t, keyRingTestPublic.GetKeys()[0].GetFingerprint(), verification.Fingerprint)
+	assert.Exactly(t, testIdentity, verification.Identity)
+
+	serialized, err := json.Marshal(verification)
+	if err != nil {
+		t.Fatal("Expected no error when serializing verification, got:", err)
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(serialized, &fields); err != nil {
+		t.Fatal("Expected no error when parsing serialized verification, got:", err)
+	}
+	assert.Exactly(t, "valid", fields["status"])
+	assert.Exactly(t, verification.Fingerprint, fields["fingerprint"])
+	assert.Exactly(t, verification.KeyID, fields["keyId"])
+	assert.Contains(t, fields, "identity")
+	assert.Contains(t, fields, "creationTime")
+
+	verification, err = keyRingTestPublic.VerifyDetachedWithDetails(NewPlainMessageFromString("wrong text"), signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusBad, verification.Status)
+
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	verification, err = ecKeyRing.VerifyDetachedWithDetails(signedMessage, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusNoVerifier, verification.Status)
+	assert.Exactly(t, "", verification.Fingerprint)
+
+	verification, err = keyRingTestPublic.VerifyDetachedWithDetails(signedMessage, NewPGPSignature([]byte("not a signature")), GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusNoSignature, verification.Status)
+
+	var expiring bytes.Buffer
+	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator(), SigLifetimeSecs: 60}
+	if err = openpgp.DetachSign(&expiring, keyRingTestPrivate.entities[0], signedMessage.NewReader(), config); err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	verification, err = keyRingTestPublic.VerifyDetachedWithDetails(signedMessage, NewPGPSignature(expiring.Bytes()), GetUnixTime()+3600)
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusExpired, verification.Status)
+
+	revokedKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error when copying keyring, got:", err)
+	}
+	revokedKeyRing.entities[0].Revocations = []*packet.Signature{{SigType: packet.SigTypeKeyRevocation}}
+	verification, err = revokedKeyRing.VerifyDetachedWithDetails(signedMessage, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusRevoked, verification.Status)
+
+	expiredKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error when copying keyring, got:", err)
+	}
+	lifetime := uint32(1)
+	for _, identity := range expiredKeyRing.entities[0].Identities {
+		identity.SelfSignature.KeyLifetimeSecs = &lifetime
+	}
+	verification, err = expiredKeyRing.VerifyDetachedWithDetails(signedMessage, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusExpired, verification.Status)
+
+	// Keys with the issuer key ID but another fingerprint are not the signer
+	impostor := *keyTestRSA.entity
+	impostorKey := *impostor.PrimaryKey
+	impostorKey.KeyId = keyRingTestPublic.entities[0].PrimaryKey.KeyId
+	impostor.PrimaryKey = &impostorKey
+	impostorKeyRing := &KeyRing{entities: openpgp.EntityList{&impostor}}
+	verification, err = impostorKeyRing.VerifyDetachedWithDetails(signedMessage, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusNoVerifier, verification.Status)
+
+	serialized, err = json.Marshal(SignatureStatusNoSignature)
+	if err != nil {
+		t.Fatal("Expected no error when serializing status, got:", err)
+	}
+	assert.Exactly(t, `"no-signature"`, string(serialized))
+}
+
+func TestVerifyDetachedStreamWithDetails(t *testing.T) {
+	data := bytes.Repeat([]byte("streamed data "), 10000)
+	signature, err := keyRingTestPrivate.SignDetachedStream(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	armored, err := signature.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring signature, got:", err)
+	}
+	armoredSignature, err := NewPGPSignatureFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring signature, got:", err)
+	}
+
+	verification, err := keyRingTestPublic.VerifyDetachedStreamWithDetails(bytes.NewReader(data), armoredSignature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusValid, verification.Status)
+	assert.Exactly(t, keyRingTestPublic.GetKeys()[0].GetFingerprint(), verification.Fingerprint)
+
+	verification, err = keyRingTestPublic.VerifyDetachedStreamWithDetails(bytes.NewReader(data[1:]), signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusBad, verification.Status)
+}
+
+func TestVerifyDetachedWithDetailsRevokedSubkey(t *testing.T) {
+	key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error when generating key, got:", err)
+	}
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA, Time: getKeyGenerationTimeGenerator()}
+	if err = key.entity.AddSigningSubkey(config); err != nil {
+		t.Fatal("Expected no error when adding signing subkey, got:", err)
+	}
+	signingSubkey := key.entity.Subkeys[len(key.entity.Subkeys)-1].PublicKey.KeyId
+
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error when building keyring, got:", err)
+	}
+	signedMessage := NewPlainMessageFromString(signedPlainText)
+	signature, err := keyRing.SignDetachedWithKeyID(signedMessage, signingSubkey)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+
+	verification, err := keyRing.VerifyDetachedWithDetails(signedMessage, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusValid, verification.Status)
+	assert.Exactly(t, keyIDToHex(signingSubkey), verification.KeyID)
+
+	// The binding signature of a revoked subkey is its revocation signature,
+	// which need not have a reason
+	revokedKeyRing, err := keyRing.Copy()
+	if err != nil {
+		t.Fatal("Expected no error when copying keyring, got:", err)
+	}
+	subkeys := revokedKeyRing.entities[0].Subkeys
+	subkeys[len(subkeys)-1].Sig = &packet.Signature{SigType: packet.SigTypeSubkeyRevocation}
+	verification, err = revokedKeyRing.VerifyDetachedWithDetails(signedMessage, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.Exactly(t, SignatureStatusRevoked, verification.Status)
+}
@@ -11,6 +11,20 @@ func UpdateTime(newTime int64) {
 	}
 }
 
+// FreezeTime sets the cached time, even if it is before the latest cached
+// time, so that the time used for messages and signatures is reproducible.
+// The time stays frozen until the next call to UpdateTime with a later time.
+func FreezeTime(newTime int64) {
+	pgp.latestServerTime = newTime
+}
+
+// SetTimeOffset updates the offset, in seconds, added to the current time for
+// messages and signatures, e.g. to compensate a skewed clock.
+// It does not apply to key generation, see SetKeyGenerationOffset.
+func SetTimeOffset(offset int64) {
+	pgp.timeOffset = offset
+}
+
 // SetKeyGenerationOffset updates the offset when generating keys.
 func SetKeyGenerationOffset(offset int64) {
 	pgp.generationOffset = offset
@@ -28,13 +42,13 @@ func GetTime() time.Time {
 
 // ----- INTERNAL FUNCTIONS -----
 
-// getNow returns the latest server time.
+// getNow returns the latest server time, with the time offset.
 func getNow() time.Time {
 	if pgp.latestServerTime == 0 {
-		return time.Now()
+		return time.Now().Add(time.Duration(pgp.timeOffset) * time.Second)
 	}
 
-	return time.Unix(pgp.latestServerTime, 0)
+	return time.Unix(pgp.latestServerTime+pgp.timeOffset, 0)
 }
 
 // getTimeGenerator Returns a time generator function.
@@ -15,3 +15,23 @@ func TestTime(t *testing.T) {
 	assert.Exactly(t, int64(1571072494), now) // Use latest server time
 	UpdateTime(testTime)
 }
+
+func TestFreezeTimeAndOffset(t *testing.T) {
+	FreezeTime(1557000000)
+	assert.Exactly(t, int64(1557000000), GetUnixTime())
+
+	SetTimeOffset(-60)
+	assert.Exactly(t, int64(1557000000-60), GetUnixTime())
+
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString("plain text"))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	creationTime, ok := signature.GetCreationTime()
+	assert.True(t, ok)
+	assert.Exactly(t, int64(1557000000-60), creationTime)
+
+	SetTimeOffset(0)
+	FreezeTime(testTime)
+	assert.Exactly(t, int64(testTime), GetUnixTime())
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrLockedOut is returned by Unlocker.Try once the maximum number of failed
+// attempts is reached, until the Unlocker is reset.
+var ErrLockedOut = errors.New("gopenpgp: too many failed unlock attempts")
+
+// Unlocker keeps track of the failed attempts to unlock a private key,
+// and refuses any further attempt once the maximum number of failed
+// attempts is reached.
+// The key itself is never modified, each attempt operates on a copy.
+type Unlocker struct {
+	mutex          sync.Mutex
+	key            *Key
+	unlockedKey    *Key
+	maxAttempts    int
+	failedAttempts int
+}
+
+// NewUnlocker creates an Unlocker for the given locked private key, allowing
+// at most maxAttempts failed attempts.
+func NewUnlocker(key *Key, maxAttempts int) (*Unlocker, error) {
+	if maxAttempts <= 0 {
+		return nil, errors.New("gopenpgp: the maximum number of attempts must be positive")
+	}
+
+	locked, err := key.IsLocked()
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, errors.New("gopenpgp: key is not locked")
+	}
+
+	return &Unlocker{
+		key:         key,
+		maxAttempts: maxAttempts,
+	}, nil
+}
+
+// Try attempts to unlock the key with the given passphrase, and returns
+// whether it succeeded and how many attempts are left.
+// Once no attempts are left, ErrLockedOut is returned until Reset is called.
+// A successful attempt resets the count of failed attempts.
+func (unlocker *Unlocker) Try(passphrase []byte) (ok bool, attemptsLeft int, err error) {
+	unlocker.mutex.Lock()
+	defer unlocker.mutex.Unlock()
+
+	if unlocker.failedAttempts >= unlocker.maxAttempts {
+		return false, 0, ErrLockedOut
+	}
+
+	unlockedKey, err := unlocker.key.Unlock(passphrase)
+	if err != nil {
+		unlocker.failedAttempts++
+		return false, unlocker.maxAttempts - unlocker.failedAttempts, nil
+	}
+
+	if unlocker.unlockedKey != nil {
+		unlocker.unlockedKey.ClearPrivateParams()
+	}
+	unlocker.unlockedKey = unlockedKey
+	unlocker.failedAttempts = 0
+	return true, unlocker.maxAttempts, nil
+}
+
+// GetUnlockedKey returns the key unlocked by the last successful attempt.
+func (unlocker *Unlocker) GetUnlockedKey() (*Key, error) {
+	unlocker.mutex.Lock()
+	defer unlocker.mutex.Unlock()
+
+	if unlocker.unlockedKey == nil {
+		return nil, errors.New("gopenpgp: key has not been unlocked")
+	}
+	return unlocker.unlockedKey, nil
+}
+
+// Reset resets the count of failed attempts.
+func (unlocker *Unlocker) Reset() {
+	unlocker.mutex.Lock()
+	defer unlocker.mutex.Unlock()
+
+	unlocker.failedAttempts = 0
+}
@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnlocker(t *testing.T) {
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+
+	_, err = NewUnlocker(lockedKey, 0)
+	assert.NotNil(t, err)
+
+	unlocker, err := NewUnlocker(lockedKey, 2)
+	if err != nil {
+		t.Fatal("Expected no error while creating unlocker, got:", err)
+	}
+
+	ok, attemptsLeft, err := unlocker.Try([]byte("wrong"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Exactly(t, 1, attemptsLeft)
+
+	ok, attemptsLeft, err = unlocker.Try([]byte("wrong"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Exactly(t, 0, attemptsLeft)
+
+	ok, _, err = unlocker.Try(testMailboxPassword)
+	assert.True(t, errors.Is(err, ErrLockedOut))
+	assert.False(t, ok)
+
+	_, err = unlocker.GetUnlockedKey()
+	assert.NotNil(t, err)
+
+	isLocked, err := lockedKey.IsLocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking lock status, got:", err)
+	}
+	assert.True(t, isLocked)
+
+	unlocker.Reset()
+	ok, attemptsLeft, err = unlocker.Try(testMailboxPassword)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Exactly(t, 2, attemptsLeft)
+
+	unlockedKey, err := unlocker.GetUnlockedKey()
+	if err != nil {
+		t.Fatal("Expected no error while getting unlocked key, got:", err)
+	}
+	isUnlocked, err := unlockedKey.IsUnlocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking lock status, got:", err)
+	}
+	assert.True(t, isUnlocked)
+}
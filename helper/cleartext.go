@@ -1,6 +1,8 @@
 package helper
 
 import (
+	"strings"
+
 	"github.com/ProtonMail/gopenpgp/v2/crypto"
 	"github.com/pkg/errors"
 )
@@ -76,3 +78,30 @@ func VerifyCleartextMessage(keyRing *crypto.KeyRing, armored string, verifyTime
 
 	return message.GetString(), nil
 }
+
+// StripSignature returns the payload of an armored inline signed or cleartext
+// signed message, discarding the signature without verifying it.
+func StripSignature(signedMessage string) (string, error) {
+	if crypto.IsPGPSignedMessage(signedMessage) {
+		clearTextMessage, err := crypto.NewClearTextMessageFromArmored(signedMessage)
+		if err != nil {
+			return "", errors.Wrap(err, "gopenpgp: unable to unarmor cleartext message")
+		}
+
+		// The signed text uses canonical CRLF line endings, return LF line
+		// endings like VerifyCleartextMessage.
+		return strings.ReplaceAll(clearTextMessage.GetString(), "\r\n", "\n"), nil
+	}
+
+	pgpMessage, err := crypto.NewPGPMessageFromArmored(signedMessage)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to unarmor signed message")
+	}
+
+	message, err := pgpMessage.StripSignature()
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to strip signature")
+	}
+
+	return message.GetString(), nil
+}
@@ -1,9 +1,11 @@
 package helper
 
 import (
+	"bytes"
 	"regexp"
 	"testing"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/gopenpgp/v2/internal"
 
 	"github.com/ProtonMail/gopenpgp/v2/crypto"
@@ -47,3 +49,50 @@ func TestSignClearText(t *testing.T) {
 	}
 	assert.Exactly(t, internal.CanonicalizeAndTrim(inputPlainText), string(clearTextMessage.GetBinary()))
 }
+
+func TestStripSignature(t *testing.T) {
+	armored, err := SignCleartextMessageArmored(
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword,
+		inputPlainText,
+	)
+	if err != nil {
+		t.Fatal("Cannot armor message:", err)
+	}
+
+	stripped, err := StripSignature(armored)
+	if err != nil {
+		t.Fatal("Cannot strip signature:", err)
+	}
+	assert.Exactly(t, signedPlainText, stripped)
+
+	var signed bytes.Buffer
+	privateKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Cannot read private key:", err)
+	}
+	unlockedKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Cannot unlock private key:", err)
+	}
+	plaintextWriter, err := openpgp.Sign(&signed, unlockedKey.GetEntity(), nil, nil)
+	if err != nil {
+		t.Fatal("Cannot sign message:", err)
+	}
+	_, _ = plaintextWriter.Write([]byte("inline signed"))
+	_ = plaintextWriter.Close()
+
+	armored, err = crypto.NewPGPMessage(signed.Bytes()).GetArmored()
+	if err != nil {
+		t.Fatal("Cannot armor message:", err)
+	}
+
+	stripped, err = StripSignature(armored)
+	if err != nil {
+		t.Fatal("Cannot strip signature:", err)
+	}
+	assert.Exactly(t, "inline signed", stripped)
+
+	_, err = StripSignature("not a signed message")
+	assert.NotNil(t, err)
+}
@@ -137,6 +137,51 @@ func DecryptVerifyMessageArmored(
 	return message.GetString(), nil
 }
 
+// DecryptStringIfNeeded returns the text of input if it is an armored PGP
+// message, decrypted with keyRing, or an armored cleartext signed message.
+// Any other string is returned unchanged.
+// The signature of the message is verified if verifyKeyRing is not nil, and
+// verified is true only if the verification succeeded: a message that fails
+// verification is still returned, without error.
+func DecryptStringIfNeeded(
+	keyRing, verifyKeyRing *crypto.KeyRing, input string, verifyTime int64,
+) (text string, verified bool, err error) {
+	var message *crypto.PlainMessage
+
+	switch {
+	case crypto.IsPGPMessage(input):
+		pgpMessage, err := crypto.NewPGPMessageFromArmored(input)
+		if err != nil {
+			return "", false, errors.Wrap(err, "gopenpgp: unable to unarmor ciphertext")
+		}
+
+		message, err = keyRing.Decrypt(pgpMessage, verifyKeyRing, verifyTime)
+		if err != nil && !isSignatureVerificationError(err) {
+			return "", false, errors.Wrap(err, "gopenpgp: unable to decrypt message")
+		}
+		return message.GetString(), verifyKeyRing != nil && err == nil, nil
+	case crypto.IsPGPSignedMessage(input):
+		clearTextMessage, err := crypto.NewClearTextMessageFromArmored(input)
+		if err != nil {
+			return "", false, errors.Wrap(err, "gopenpgp: unable to unarmor cleartext message")
+		}
+
+		message = crypto.NewPlainMessageFromString(clearTextMessage.GetString())
+		if verifyKeyRing == nil {
+			return message.GetString(), false, nil
+		}
+
+		signature := crypto.NewPGPSignature(clearTextMessage.GetBinarySignature())
+		err = verifyKeyRing.VerifyDetached(message, signature, verifyTime)
+		if err != nil && !isSignatureVerificationError(err) {
+			return "", false, errors.Wrap(err, "gopenpgp: unable to verify cleartext message")
+		}
+		return message.GetString(), err == nil, nil
+	default:
+		return input, false, nil
+	}
+}
+
 // DecryptVerifyAttachment decrypts and verifies an attachment split into the
 // keyPacket, dataPacket and an armored (!) signature, given a publicKey, and a
 // privateKey with its passphrase. Returns the plain data or an error on
@@ -276,6 +321,32 @@ func DecryptVerifyBinaryDetached(
 	return message.GetBinary(), nil
 }
 
+// SignDetachedArmoredWithComment signs a message given a private key and its
+// passphrase, and returns the armored detached signature with the given
+// Comment header. If canonicalizeText is true the message is signed as
+// canonicalized text, otherwise as is.
+func SignDetachedArmoredWithComment(
+	privateKey string,
+	passphrase []byte,
+	plaintext string,
+	canonicalizeText bool,
+	comment string,
+) (string, error) {
+	var message *crypto.PlainMessage
+	if canonicalizeText {
+		message = crypto.NewPlainMessageFromString(plaintext)
+	} else {
+		message = crypto.NewPlainMessage([]byte(plaintext))
+	}
+
+	signature, err := signDetached(privateKey, passphrase, message)
+	if err != nil {
+		return "", err
+	}
+
+	return signature.GetArmoredWithCustomHeaders(comment, "")
+}
+
 // EncryptAttachmentWithKey encrypts a binary file
 // Using a given armored public key.
 func EncryptAttachmentWithKey(
@@ -598,3 +669,8 @@ func decryptVerifyObjDetached(
 
 	return message, nil
 }
+
+func isSignatureVerificationError(err error) bool {
+	var verificationError crypto.SignatureVerificationError
+	return errors.As(err, &verificationError)
+}
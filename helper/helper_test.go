@@ -217,6 +217,42 @@ func TestEncryptSignArmoredDetached(t *testing.T) {
 	}
 }
 
+func TestSignDetachedArmoredWithComment(t *testing.T) {
+	var plaintext = "Release 1.0.0\n"
+
+	armoredSignature, err := SignDetachedArmoredWithComment(
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword, // Password defined in base_test
+		plaintext,
+		true,
+		"Signed by Release Bot",
+	)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+
+	assert.Contains(t, armoredSignature, "\nComment: Signed by Release Bot\n")
+
+	check, err := verifyDetachedArmored(
+		readTestFile("keyring_publicKey", false),
+		crypto.NewPlainMessageFromString(plaintext),
+		armoredSignature,
+	)
+	if err != nil {
+		t.Fatal("Expected no error when verifying, got:", err)
+	}
+	assert.True(t, check)
+
+	_, err = SignDetachedArmoredWithComment(
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword,
+		plaintext,
+		true,
+		"Signed by Release Bot\n\nforged",
+	)
+	assert.NotNil(t, err)
+}
+
 func TestEncryptDecryptAttachmenWithKey(t *testing.T) {
 	plainData := []byte("Secret message")
 	privateKeyString := readTestFile("keyring_privateKey", false)
@@ -352,3 +388,73 @@ func TestEncryptSignBinaryDetached(t *testing.T) {
 		t.Fatal("Expected an error while decrypting and verifying with a wrong signature")
 	}
 }
+
+func TestDecryptStringIfNeeded(t *testing.T) {
+	privateKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when reading key, got:", err)
+	}
+	unlockedKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error when unlocking key, got:", err)
+	}
+	privateKeyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error when creating keyring, got:", err)
+	}
+	publicKeyRing, err := createPublicKeyRing(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when creating keyring, got:", err)
+	}
+	otherKeyRing, err := createPublicKeyRing(readTestFile("mime_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when creating keyring, got:", err)
+	}
+
+	var plaintext = "Secret message"
+	ciphertext, err := privateKeyRing.Encrypt(crypto.NewPlainMessageFromString(plaintext), privateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	armored, err := ciphertext.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	signed, err := SignCleartextMessage(privateKeyRing, plaintext)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+
+	for _, input := range []string{armored, signed} {
+		text, verified, err := DecryptStringIfNeeded(privateKeyRing, publicKeyRing, input, testTime)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, plaintext, text)
+		assert.True(t, verified)
+
+		text, verified, err = DecryptStringIfNeeded(privateKeyRing, otherKeyRing, input, testTime)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, plaintext, text)
+		assert.False(t, verified)
+
+		text, verified, err = DecryptStringIfNeeded(privateKeyRing, nil, input, testTime)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, plaintext, text)
+		assert.False(t, verified)
+	}
+
+	text, verified, err := DecryptStringIfNeeded(privateKeyRing, publicKeyRing, plaintext, testTime)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, plaintext, text)
+	assert.False(t, verified)
+
+	_, _, err = DecryptStringIfNeeded(otherKeyRing, nil, armored, testTime)
+	assert.NotNil(t, err)
+}
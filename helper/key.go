@@ -35,6 +35,18 @@ func UpdatePrivateKeyPassphrase(
 	return armored, nil
 }
 
+// IsPassphraseProtected returns whether the given armored privateKey is
+// protected by a passphrase, i.e. needs to be unlocked before use.
+// Keys without a passphrase can be used directly, without prompting for one.
+func IsPassphraseProtected(privateKey string) (bool, error) {
+	key, err := crypto.NewKeyFromArmored(privateKey)
+	if err != nil {
+		return false, errors.Wrap(err, "gopenpgp: unable to parse key")
+	}
+
+	return key.IsLocked()
+}
+
 // GenerateKey generates a key of the given keyType ("rsa" or "x25519"), encrypts it, and returns an armored string.
 // If keyType is "rsa", bits is the RSA bitsize of the key.
 // If keyType is "x25519" bits is unused.
@@ -53,6 +65,47 @@ func GenerateKey(name, email string, passphrase []byte, keyType string, bits int
 	return locked.Armor()
 }
 
+// GenerateKeyWithIdentities generates a key of the given keyType ("rsa" or "x25519")
+// with all the given identities, the first one being primary, encrypts it,
+// and returns an armored string.
+// If keyType is "rsa", bits is the RSA bitsize of the key.
+// If keyType is "x25519" bits is unused.
+func GenerateKeyWithIdentities(identities []*crypto.Identity, passphrase []byte, keyType string, bits int) (string, error) {
+	key, err := crypto.GenerateKeyWithIdentities(identities, keyType, bits)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to generate new key")
+	}
+	defer key.ClearPrivateParams()
+
+	locked, err := key.Lock(passphrase)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to lock new key")
+	}
+
+	return locked.Armor()
+}
+
+// GenerateCertifyOnlyKey generates a key of the given keyType ("rsa" or "x25519")
+// with all the given identities, whose primary key can only certify, with a
+// signing and an encryption subkey. It encrypts the key, and returns an
+// armored string.
+// If keyType is "rsa", bits is the RSA bitsize of the keys.
+// If keyType is "x25519" bits is unused.
+func GenerateCertifyOnlyKey(identities []*crypto.Identity, passphrase []byte, keyType string, bits int) (string, error) {
+	key, err := crypto.GenerateCertifyOnlyKey(identities, keyType, bits)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to generate new key")
+	}
+	defer key.ClearPrivateParams()
+
+	locked, err := key.Lock(passphrase)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to lock new key")
+	}
+
+	return locked.Armor()
+}
+
 func GetSHA256Fingerprints(publicKey string) ([]string, error) {
 	key, err := crypto.NewKeyFromArmored(publicKey)
 	if err != nil {
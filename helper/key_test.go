@@ -3,6 +3,7 @@ package helper
 import (
 	"testing"
 
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,3 +17,123 @@ func TestGetSHA256FingerprintsV4(t *testing.T) {
 	assert.Exactly(t, "d9ac0b857da6d2c8be985b251a9e3db31e7a1d2d832d1f07ebe838a9edce9c24", sha256Fingerprints[0])
 	assert.Exactly(t, "203dfba1f8442c17e59214d9cd11985bfc5cc8721bb4a71740dd5507e58a1a0d", sha256Fingerprints[1])
 }
+
+func TestGenerateKeyWithIdentities(t *testing.T) {
+	identities := []*crypto.Identity{
+		{Name: "Max Mustermann", Email: "max.mustermann@work.example"},
+		{Name: "Max", Email: "max@home.example"},
+	}
+
+	armored, err := GenerateKeyWithIdentities(identities, testMailboxPassword, "x25519", 256)
+	if err != nil {
+		t.Fatal("Cannot generate key:", err)
+	}
+
+	key, err := crypto.NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+
+	unlockedKey, err := key.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Cannot unlock key:", err)
+	}
+
+	keyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Cannot create keyring:", err)
+	}
+
+	assert.ElementsMatch(t, identities, keyRing.GetIdentities())
+	assert.Exactly(t, "max.mustermann@work.example", key.GetEntity().PrimaryIdentity().UserId.Email)
+
+	_, err = GenerateKeyWithIdentities(nil, testMailboxPassword, "x25519", 256)
+	assert.NotNil(t, err)
+}
+
+func TestGenerateCertifyOnlyKey(t *testing.T) {
+	identities := []*crypto.Identity{{Name: "Max Mustermann", Email: "max.mustermann@work.example"}}
+
+	armored, err := GenerateCertifyOnlyKey(identities, testMailboxPassword, "x25519", 256)
+	if err != nil {
+		t.Fatal("Cannot generate key:", err)
+	}
+
+	key, err := crypto.NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+
+	primarySignature := key.GetEntity().PrimaryIdentity().SelfSignature
+	assert.True(t, primarySignature.FlagCertify)
+	assert.False(t, primarySignature.FlagSign)
+	assert.False(t, primarySignature.FlagEncryptCommunications)
+	assert.False(t, primarySignature.FlagEncryptStorage)
+
+	subkeys := key.GetEntity().Subkeys
+	assert.Len(t, subkeys, 2)
+	assert.True(t, subkeys[0].Sig.FlagEncryptCommunications)
+	assert.True(t, subkeys[1].Sig.FlagSign)
+
+	unlockedKey, err := key.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Cannot unlock key:", err)
+	}
+	keyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Cannot create keyring:", err)
+	}
+
+	message := crypto.NewPlainMessageFromString("certify only")
+	signature, err := keyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Cannot sign message:", err)
+	}
+	subkeyID, ok := keyRing.SigningSubkeyID(signature)
+	assert.True(t, ok)
+	assert.Exactly(t, subkeys[1].PublicKey.KeyId, subkeyID)
+	assert.Nil(t, keyRing.VerifyDetached(message, signature, crypto.GetUnixTime()))
+
+	ciphertext, err := keyRing.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Cannot encrypt message:", err)
+	}
+	decrypted, err := keyRing.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Cannot decrypt message:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+}
+
+func TestIsPassphraseProtected(t *testing.T) {
+	isProtected, err := IsPassphraseProtected(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while checking key, got:", err)
+	}
+	assert.True(t, isProtected)
+
+	locked, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+	unlocked, err := locked.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Cannot unlock key:", err)
+	}
+	armored, err := unlocked.Armor()
+	if err != nil {
+		t.Fatal("Cannot armor key:", err)
+	}
+
+	isProtected, err = IsPassphraseProtected(armored)
+	if err != nil {
+		t.Fatal("Expected no error while checking key, got:", err)
+	}
+	assert.False(t, isProtected)
+
+	_, err = IsPassphraseProtected(readTestFile("keyring_publicKey", false))
+	assert.NotNil(t, err)
+
+	_, err = IsPassphraseProtected("not a key")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,102 @@
+package subtle
+
+import (
+	"bytes"
+	"crypto/aes"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// legacyDataPacketTag is the tag of the Symmetrically Encrypted Data Packet,
+// see RFC 4880, section 5.7.
+const legacyDataPacketTag = 9
+
+// EncryptLegacyNoMDC encrypts a PlainMessage to the given public keyring using
+// the legacy Symmetrically Encrypted Data packet (tag 9) instead of the
+// Symmetrically Encrypted Integrity Protected Data packet (tag 18).
+//
+// WARNING: the output has NO integrity protection. An attacker can modify the
+// ciphertext without the recipient noticing, and in some settings this leads
+// to plaintext recovery. This function only exists to interoperate with
+// legacy devices that cannot process integrity protected packets, and must
+// not be used for anything else. iUnderstandThisIsInsecure must be set to
+// true, otherwise no encryption is performed and an error is returned.
+// Note that the decryption functions of this library refuse such messages.
+func EncryptLegacyNoMDC(
+	message *crypto.PlainMessage,
+	publicKey *crypto.KeyRing,
+	iUnderstandThisIsInsecure bool,
+) (*crypto.PGPMessage, error) {
+	if !iUnderstandThisIsInsecure {
+		return nil, errors.New("gopenpgp: refusing to encrypt without integrity protection")
+	}
+
+	sessionKey, err := crypto.GenerateSessionKeyAlgo(constants.AES256)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to generate session key")
+	}
+	defer sessionKey.Clear()
+
+	keyPacket, err := publicKey.EncryptSessionKey(sessionKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt session key")
+	}
+
+	var literal bytes.Buffer
+	literalWriter, err := packet.SerializeLiteral(noOpCloser{&literal}, message.IsBinary(), message.Filename, message.Time)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to serialize literal data")
+	}
+	if _, err = literalWriter.Write(message.GetBinary()); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in writing literal data")
+	}
+	if err = literalWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in closing literal data")
+	}
+
+	block, err := aes.NewCipher(sessionKey.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to create cipher")
+	}
+
+	randData, err := crypto.RandomToken(block.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+
+	stream, prefix := packet.NewOCFBEncrypter(block, randData, packet.OCFBResync)
+	ciphertext := make([]byte, literal.Len())
+	stream.XORKeyStream(ciphertext, literal.Bytes())
+
+	var dataPacket bytes.Buffer
+	bodyLength := len(prefix) + len(ciphertext)
+	// New format header with a five-octet body length, see RFC 4880, section 4.2.2.3.
+	dataPacket.Write([]byte{
+		0xc0 | legacyDataPacketTag,
+		0xff,
+		byte(bodyLength >> 24),
+		byte(bodyLength >> 16),
+		byte(bodyLength >> 8),
+		byte(bodyLength),
+	})
+	dataPacket.Write(prefix)
+	dataPacket.Write(ciphertext)
+
+	return crypto.NewPGPMessage(append(keyPacket, dataPacket.Bytes()...)), nil
+}
+
+type noOpCloser struct {
+	w io.Writer
+}
+
+func (c noOpCloser) Write(data []byte) (n int, err error) {
+	return c.w.Write(data)
+}
+
+func (c noOpCloser) Close() error {
+	return nil
+}
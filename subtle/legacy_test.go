@@ -0,0 +1,148 @@
+package subtle
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestFile(name string) string {
+	data, err := ioutil.ReadFile("../crypto/testdata/" + name) //nolint
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// splitPackets splits a sequence of new format packets into (tag, body) pairs.
+func splitPackets(t *testing.T, data []byte) (tags []byte, bodies [][]byte) {
+	for len(data) > 0 {
+		if data[0]&0xc0 != 0xc0 {
+			t.Fatal("Expected new format packet header")
+		}
+		tag := data[0] & 0x3f
+		var length, headerLength int
+		switch {
+		case data[1] < 192:
+			length, headerLength = int(data[1]), 2
+		case data[1] < 224:
+			length, headerLength = (int(data[1])-192)<<8+int(data[2])+192, 3
+		case data[1] == 255:
+			length = int(data[2])<<24 | int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+			headerLength = 6
+		default:
+			t.Fatal("Unexpected partial body length")
+		}
+		tags = append(tags, tag)
+		bodies = append(bodies, data[headerLength:headerLength+length])
+		data = data[headerLength+length:]
+	}
+	return
+}
+
+func TestSubtle_EncryptLegacyNoMDC(t *testing.T) {
+	privateKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey"))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+
+	unlockedKey, err := privateKey.Unlock([]byte("apple"))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking private key, got:", err)
+	}
+	defer unlockedKey.ClearPrivateParams()
+
+	keyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	message := crypto.NewPlainMessageFromString("some legacy plaintext")
+
+	_, err = EncryptLegacyNoMDC(message, keyRing, false)
+	assert.NotNil(t, err)
+
+	encrypted, err := EncryptLegacyNoMDC(message, keyRing, true)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	tags, bodies := splitPackets(t, encrypted.GetBinary())
+	assert.Exactly(t, []byte{1, legacyDataPacketTag}, tags)
+
+	sessionKey, err := keyRing.DecryptSessionKey(encrypted.GetBinary())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+
+	block, err := aes.NewCipher(sessionKey.Key)
+	if err != nil {
+		t.Fatal("Expected no error while creating cipher, got:", err)
+	}
+
+	dataPacket := bodies[1]
+	prefix := clone(dataPacket[:block.BlockSize()+2])
+	stream := packet.NewOCFBDecrypter(block, prefix, packet.OCFBResync)
+	if stream == nil {
+		t.Fatal("Expected session key to match the data packet")
+	}
+	plaintext := make([]byte, len(dataPacket)-len(prefix))
+	stream.XORKeyStream(plaintext, dataPacket[len(prefix):])
+
+	p, err := packet.Read(bytes.NewReader(plaintext))
+	if err != nil {
+		t.Fatal("Expected no error while reading literal data, got:", err)
+	}
+	literalData, ok := p.(*packet.LiteralData)
+	if !ok {
+		t.Fatal("Expected a literal data packet")
+	}
+	body, err := ioutil.ReadAll(literalData.Body)
+	if err != nil {
+		t.Fatal("Expected no error while reading literal data body, got:", err)
+	}
+
+	assert.False(t, literalData.IsBinary)
+	assert.Exactly(t, message.GetBinary(), body)
+}
+
+func TestSubtle_DecryptSecureLegacyNoMDC(t *testing.T) {
+	privateKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey"))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+
+	unlockedKey, err := privateKey.Unlock([]byte("apple"))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking private key, got:", err)
+	}
+	defer unlockedKey.ClearPrivateParams()
+
+	keyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	encrypted, err := EncryptLegacyNoMDC(crypto.NewPlainMessageFromString("some legacy plaintext"), keyRing, true)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	_, err = keyRing.DecryptSecure(encrypted, keyRing, crypto.GetUnixTime())
+	assert.True(t, errors.Is(err, crypto.ErrMissingIntegrityProtection))
+
+	_, err = keyRing.DecryptSecureStream(bytes.NewReader(encrypted.GetBinary()), keyRing, crypto.GetUnixTime())
+	assert.True(t, errors.Is(err, crypto.ErrMissingIntegrityProtection))
+}
+
+func clone(input []byte) []byte {
+	data := make([]byte, len(input))
+	copy(data, input)
+	return data
+}